@@ -0,0 +1,208 @@
+// Command logq reads, filters and follows log files written by logutil loggers.
+//
+// Usage:
+//
+//	logq [flags] [file ...]
+//
+// The files are read in order. When no file is given, the file named by -file in -dir is read
+// after its rotated backups. Entries are printed as tab separated text, or as JSON with -o json.
+//
+// Examples:
+//
+//	logq -dir /var/log/app -file app.log -level warn -since 1h
+//	logq -logger http -field status=500 -o json app.log
+//	logq -n 20 -f app.log
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/justmumu/goutils/logutil"
+)
+
+// fieldFlags collects the repeated -field key=value flags.
+type fieldFlags map[string]string
+
+func (f fieldFlags) String() string {
+	pairs := make([]string, 0, len(f))
+	for key, value := range f {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f fieldFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("field filter must be key=value: %q", s)
+	}
+	f[key] = value
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "logq:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("logq", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "log directory searched when no file is given")
+	file := fs.String("file", "", "log filename in -dir, read after its rotated backups")
+	follow := fs.Bool("f", false, "follow the last file as entries are appended")
+	tail := fs.Int("n", 0, "print only the last n matching entries")
+	since := fs.String("since", "", "skip entries before this RFC3339 time or duration ago, e.g. 30m")
+	until := fs.String("until", "", "skip entries after this RFC3339 time or duration ago")
+	level := fs.String("level", "debug", "minimum level of the entries")
+	name := fs.String("logger", "", "logger name, children of the logger match too")
+	timeFormat := fs.String("time-format", "", "time layout of the entries when not ISO8601, RFC3339 or epoch")
+	output := fs.String("o", "text", "output format, text or json")
+	fields := fieldFlags{}
+	fs.Var(fields, "field", "field filter key=value, dotted keys reach into objects (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	query := logutil.LogQuery{Name: *name, Fields: fields, TimeFormat: *timeFormat}
+	var err error
+	if query.MinLevel, err = logutil.ParseLogLevel(*level); err != nil {
+		return err
+	}
+	if query.Since, err = parseTimeFlag(*since); err != nil {
+		return fmt.Errorf("invalid -since: %v", err)
+	}
+	if query.Until, err = parseTimeFlag(*until); err != nil {
+		return fmt.Errorf("invalid -until: %v", err)
+	}
+
+	var print func(logutil.LogEntry) error
+	switch *output {
+	case "text":
+		print = func(entry logutil.LogEntry) error { return printText(out, entry) }
+	case "json":
+		enc := json.NewEncoder(out)
+		print = func(entry logutil.LogEntry) error { return enc.Encode(jsonEntry(entry)) }
+	default:
+		return fmt.Errorf("unknown output format: %q", *output)
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		if *file == "" {
+			return errors.New("a file argument or -file is required")
+		}
+		if paths, err = logutil.LogFiles(logutil.LoggerConfig{LogDirectory: *dir, Filename: *file}); err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no log files of %s in %s", *file, *dir)
+		}
+	}
+
+	// Followed files are read up to their end first, the last file is then followed from there
+	read := paths
+	if *follow && *tail <= 0 {
+		read = paths[:len(paths)-1]
+	}
+
+	if *tail > 0 {
+		var last []logutil.LogEntry
+		err = logutil.ReadLogs(read, query, func(entry logutil.LogEntry) error {
+			last = append(last, entry)
+			if len(last) > *tail {
+				last = last[1:]
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, entry := range last {
+			if err := print(entry); err != nil {
+				return err
+			}
+		}
+	} else if err := logutil.ReadLogs(read, query, print); err != nil {
+		return err
+	}
+
+	if !*follow {
+		return nil
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return logutil.FollowLog(ctx, paths[len(paths)-1], query, *tail > 0, print)
+}
+
+// parseTimeFlag parses an RFC3339 time or a duration before now. Empty values return the zero time.
+func parseTimeFlag(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// printText prints the entry in the text encoding of the loggers, which logq reads back.
+func printText(w io.Writer, entry logutil.LogEntry) error {
+	parts := []string{entry.Time.Format("2006-01-02T15:04:05.000Z0700"), entry.Level.String()}
+	if entry.Name != "" {
+		parts = append(parts, entry.Name)
+	}
+	if entry.Caller != "" {
+		parts = append(parts, entry.Caller)
+	}
+	parts = append(parts, entry.Message)
+	if len(entry.Fields) > 0 {
+		fields, err := json.Marshal(entry.Fields)
+		if err != nil {
+			return fmt.Errorf("could not encode fields. err: %v", err)
+		}
+		parts = append(parts, string(fields))
+	}
+
+	line := strings.Join(parts, "\t")
+	if entry.Stacktrace != "" {
+		line += "\n" + entry.Stacktrace
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// jsonEntry returns the entry with the keys of the JSON encoding of the loggers.
+func jsonEntry(entry logutil.LogEntry) map[string]interface{} {
+	m := make(map[string]interface{}, len(entry.Fields)+6)
+	for key, value := range entry.Fields {
+		m[key] = value
+	}
+	m["ts"] = entry.Time.Format("2006-01-02T15:04:05.000Z0700")
+	m["level"] = entry.Level
+	m["msg"] = entry.Message
+	if entry.Name != "" {
+		m["logger"] = entry.Name
+	}
+	if entry.Caller != "" {
+		m["caller"] = entry.Caller
+	}
+	if entry.Stacktrace != "" {
+		m["stacktrace"] = entry.Stacktrace
+	}
+	return m
+}
@@ -0,0 +1,315 @@
+package logutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justmumu/goutils/maputil"
+)
+
+const defaultAccessLogBodySize = 4096
+
+// AccessLogFormat is the format of the entries of AccessLogMiddleware.
+type AccessLogFormat uint8
+
+const (
+	// AccessLogStructured logs the request attributes as fields.
+	AccessLogStructured AccessLogFormat = iota
+	// AccessLogCommon logs the request as an Apache common log format line.
+	AccessLogCommon
+	// AccessLogCombined logs the request as an Apache combined log format line.
+	AccessLogCombined
+)
+
+// AccessLogOptions configures AccessLogMiddleware.
+type AccessLogOptions struct {
+	// Format of the entries, defaults to AccessLogStructured
+	Format AccessLogFormat
+	// Message of the structured entries, defaults to "http request"
+	Message string
+	// Bodies adds the request, as built by maputil.NewHTTPRequestMap, and the response body to structured entries
+	Bodies bool
+	// MaxBodySize caps the logged bodies in bytes, defaults to 4 KiB. Larger bodies are truncated.
+	MaxBodySize int
+	// Skip excludes the requests it returns true for, e.g. health checks
+	Skip func(r *http.Request) bool
+}
+
+// AccessLogMiddleware returns a middleware logging every request through l once it is served.
+// Requests are logged at ErrorLevel for 5xx statuses, WarnLevel for 4xx statuses and InfoLevel
+// otherwise, along with the fields extracted from their context. Panics of the handler are logged
+// as 500 responses with a panic field and keep unwinding untouched. Hijacked connections, e.g. websocket upgrades, are logged with
+// a hijacked field once the handler returns, the bytes written to them are not counted.
+func AccessLogMiddleware(l Logger, opts AccessLogOptions) func(http.Handler) http.Handler {
+	if opts.Message == "" {
+		opts.Message = "http request"
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaultAccessLogBodySize
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.Skip != nil && opts.Skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var reqMap maputil.HTTPRequestMap
+			var reqTruncated bool
+			if opts.Bodies && opts.Format == AccessLogStructured {
+				reqMap, reqTruncated = captureRequest(r, opts.MaxBodySize)
+			}
+
+			rw := &accessLogWriter{ResponseWriter: w}
+			if opts.Bodies && opts.Format == AccessLogStructured {
+				rw.maxBody = opts.MaxBodySize
+			}
+
+			start := time.Now()
+			completed := false
+			defer func() {
+				// An unfinished handler panicked, the panic keeps unwinding with its stack
+				if !completed {
+					rw.status = http.StatusInternalServerError
+				}
+				entry := accessLogEntry{
+					request:      r,
+					writer:       rw,
+					start:        start,
+					latency:      time.Since(start),
+					reqMap:       reqMap,
+					reqTruncated: reqTruncated,
+					panicked:     !completed,
+				}
+				entry.log(l, opts)
+			}()
+			next.ServeHTTP(rw, r)
+			completed = true
+		})
+	}
+}
+
+// captureRequest returns the map of r with the body capped to max bytes and the credentials
+// masked, leaving the whole body readable by the handler.
+func captureRequest(r *http.Request, max int) (maputil.HTTPRequestMap, bool) {
+	clone := r.Clone(r.Context())
+	maskRequestCredentials(clone)
+	truncated := false
+	if r.Body != nil && r.Body != http.NoBody {
+		var head []byte
+		head, truncated, r.Body = peekBody(r.Body, max)
+		clone.Body = io.NopCloser(bytes.NewReader(head))
+	}
+
+	reqMap, err := maputil.NewHTTPRequestMap(clone)
+	if err != nil {
+		return nil, false
+	}
+	return reqMap, truncated
+}
+
+// peekBody reads up to max bytes of body and reports whether it is longer. The returned body reads
+// the whole content again, the peeked bytes included.
+func peekBody(body io.ReadCloser, max int) ([]byte, bool, io.ReadCloser) {
+	head, _ := io.ReadAll(io.LimitReader(body, int64(max)+1))
+	whole := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), body), body}
+
+	if len(head) > max {
+		return head[:max], true, whole
+	}
+	return head, false, whole
+}
+
+// credentialHeaders are the headers masked in the dumped requests and responses.
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// maskCredentialHeaders replaces the values of the credential headers of h by DefaultRedactionMask.
+// h must be a copy owned by the caller.
+func maskCredentialHeaders(h http.Header) {
+	for _, name := range credentialHeaders {
+		for i := range h[name] {
+			h[name][i] = DefaultRedactionMask
+		}
+	}
+}
+
+// maskRequestCredentials masks the credential headers and the URL password of a cloned request,
+// the password is masked like by URL.Redacted.
+func maskRequestCredentials(r *http.Request) {
+	maskCredentialHeaders(r.Header)
+	if _, ok := r.URL.User.Password(); ok {
+		r.URL.User = url.UserPassword(r.URL.User.Username(), "xxxxx")
+	}
+}
+
+// accessLogWriter records the status, size and optionally the body of a response.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status   int
+	bytes    int64
+	maxBody  int
+	body     bytes.Buffer
+	hijacked bool
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if room := w.maxBody - w.body.Len(); room > 0 {
+		w.body.Write(p[:min(room, len(p))])
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush flushes the underlying writer when it supports flushing.
+func (w *accessLogWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection of the underlying writer when it supports hijacking.
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push when the underlying writer supports it.
+func (w *accessLogWriter) Push(target string, opts *http.PushOptions) error {
+	p, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type accessLogEntry struct {
+	request      *http.Request
+	writer       *accessLogWriter
+	start        time.Time
+	latency      time.Duration
+	reqMap       maputil.HTTPRequestMap
+	reqTruncated bool
+	panicked     bool
+}
+
+func (e accessLogEntry) status() int {
+	if e.writer.status == 0 {
+		return http.StatusOK
+	}
+	return e.writer.status
+}
+
+func (e accessLogEntry) log(l Logger, opts AccessLogOptions) {
+	status := e.status()
+	level := InfoLevel
+	switch {
+	case status >= 500:
+		level = ErrorLevel
+	case status >= 400:
+		level = WarnLevel
+	}
+
+	kv := contextFields(e.request.Context())
+	if e.panicked {
+		kv = append(kv, "panic", true)
+	}
+	if e.writer.hijacked {
+		kv = append(kv, "hijacked", true)
+	}
+
+	switch opts.Format {
+	case AccessLogCommon:
+		logAt(l, level, e.commonLine(), kv...)
+	case AccessLogCombined:
+		line := fmt.Sprintf("%s %q %q", e.commonLine(), e.request.Referer(), e.request.UserAgent())
+		logAt(l, level, line, kv...)
+	default:
+		kv = append(kv,
+			"method", e.request.Method,
+			"path", e.request.URL.Path,
+			"status", status,
+			"bytes", e.writer.bytes,
+			"latency", e.latency,
+			"remote_addr", e.request.RemoteAddr,
+			"user_agent", e.request.UserAgent(),
+		)
+		if e.reqMap != nil {
+			kv = append(kv, "request", e.reqMap)
+			if e.reqTruncated {
+				kv = append(kv, "request_body_truncated", true)
+			}
+		}
+		if opts.Bodies {
+			kv = append(kv, "response_body", e.writer.body.String())
+			if e.writer.bytes > int64(e.writer.body.Len()) {
+				kv = append(kv, "response_body_truncated", true)
+			}
+		}
+		logAt(l, level, opts.Message, kv...)
+	}
+}
+
+// commonLine formats the request in the Apache common log format.
+func (e accessLogEntry) commonLine() string {
+	host, _, err := net.SplitHostPort(e.request.RemoteAddr)
+	if err != nil {
+		host = e.request.RemoteAddr
+	}
+	user := "-"
+	if name, _, ok := e.request.BasicAuth(); ok && name != "" {
+		user = name
+	} else if e.request.URL.User != nil && e.request.URL.User.Username() != "" {
+		user = e.request.URL.User.Username()
+	}
+	size := "-"
+	if e.writer.bytes > 0 {
+		size = strconv.FormatInt(e.writer.bytes, 10)
+	}
+
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+		commonLogField(host), commonLogField(user), e.start.Format("02/Jan/2006:15:04:05 -0700"),
+		e.request.Method, e.request.URL.RequestURI(), e.request.Proto, e.status(), size)
+}
+
+// commonLogField replaces the spaces of a field of the common log format.
+func commonLogField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, " ", "_")
+}
@@ -0,0 +1,151 @@
+package logutil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)}})
+	require.Nil(t, err)
+
+	handler := AccessLogMiddleware(l, AccessLogOptions{Bodies: true, MaxBodySize: 8})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.Nil(t, err)
+			require.Equal(t, "0123456789", string(body))
+			require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, "created!!")
+		}))
+
+	req := httptest.NewRequest(http.MethodPost, "/users?x=1", strings.NewReader("0123456789"))
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Authorization", "Bearer secret")
+	req = req.WithContext(ContextWithFields(req.Context(), "request_id", "abc"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	require.Equal(t, "info", entry["level"])
+	require.Equal(t, "http request", entry["msg"])
+	require.Equal(t, "abc", entry["request_id"])
+	require.Equal(t, "POST", entry["method"])
+	require.Equal(t, "/users", entry["path"])
+	require.Equal(t, float64(201), entry["status"])
+	require.Equal(t, float64(9), entry["bytes"])
+	require.Contains(t, entry, "latency")
+	require.Equal(t, "192.0.2.1:1234", entry["remote_addr"])
+	require.Equal(t, "test-agent", entry["user_agent"])
+	require.Equal(t, "01234567", entry["request"].(map[string]interface{})["request"].(map[string]interface{})["body"])
+	require.Equal(t, true, entry["request_body_truncated"])
+	require.NotContains(t, buf.String(), "Bearer secret")
+	require.Equal(t, "created!", entry["response_body"])
+	require.Equal(t, true, entry["response_body_truncated"])
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)}})
+	require.Nil(t, err)
+
+	notFound := http.NotFoundHandler()
+	req := httptest.NewRequest(http.MethodGet, "/missing?q=1", nil)
+	req.SetBasicAuth("frank", "secret")
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", "agent/1.0")
+
+	AccessLogMiddleware(l, AccessLogOptions{Format: AccessLogCommon})(notFound).ServeHTTP(httptest.NewRecorder(), req)
+	AccessLogMiddleware(l, AccessLogOptions{Format: AccessLogCombined})(notFound).ServeHTTP(httptest.NewRecorder(), req)
+	AccessLogMiddleware(l, AccessLogOptions{Skip: func(*http.Request) bool { return true }})(notFound).
+		ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+	common := `^192\.0\.2\.1 - frank \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /missing\?q=1 HTTP/1\.1" 404 19`
+	require.Equal(t, "warn", entries[0]["level"])
+	require.Regexp(t, regexp.MustCompile(common+`$`), entries[0]["msg"])
+	require.Regexp(t, regexp.MustCompile(common+` "http://example\.com/" "agent/1\.0"$`), entries[1]["msg"])
+}
+
+func TestAccessLogPanic(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)}})
+	require.Nil(t, err)
+
+	handler := AccessLogMiddleware(l, AccessLogOptions{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+	require.PanicsWithValue(t, "boom", func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, "error", entries[0]["level"])
+	require.Equal(t, float64(500), entries[0]["status"])
+	require.Equal(t, true, entries[0]["panic"])
+}
+
+func TestAccessLogHijack(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)}})
+	require.Nil(t, err)
+
+	var pusher bool
+	handler := AccessLogMiddleware(l, AccessLogOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pusher = w.(http.Pusher)
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		_ = rw.Flush()
+	}))
+	// The entry is logged once the handler returns, after the client read the response
+	served := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(served)
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	require.Nil(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "test")
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	<-served
+	require.True(t, pusher)
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, true, entries[0]["hijacked"])
+	require.Equal(t, "/ws", entries[0]["path"])
+
+	// Writers without hijacking or pushing report them as not supported
+	var hijackErr, pushErr error
+	AccessLogMiddleware(l, AccessLogOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, hijackErr = w.(http.Hijacker).Hijack()
+		pushErr = w.(http.Pusher).Push("/style.css", nil)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	require.ErrorIs(t, hijackErr, http.ErrNotSupported)
+	require.ErrorIs(t, pushErr, http.ErrNotSupported)
+	entries = decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+	require.NotContains(t, entries[1], "hijacked")
+}
@@ -0,0 +1,270 @@
+package logutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultAsyncQueueSize     = 1024
+	defaultAsyncFlushInterval = time.Second
+	defaultAsyncBufferSize    = 256 * 1024
+)
+
+var errAsyncWriterClosed = errors.New("async writer is closed")
+
+// OverflowPolicy decides what happens to entries logged while the async queue is full.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock blocks the logging call until the queue has room.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the entry being logged.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest queued entry to make room.
+	OverflowDropOldest
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", p)
+	}
+}
+
+// MarshalText marshals the OverflowPolicy to its name.
+func (p OverflowPolicy) MarshalText() ([]byte, error) {
+	if p > OverflowDropOldest {
+		return nil, fmt.Errorf("unknown overflow policy: %d", p)
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText unmarshals a case-insensitive policy name such as "drop_oldest" to an OverflowPolicy.
+func (p *OverflowPolicy) UnmarshalText(text []byte) error {
+	for policy := OverflowBlock; policy <= OverflowDropOldest; policy++ {
+		if strings.EqualFold(string(text), policy.String()) {
+			*p = policy
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown overflow policy: %q", text)
+}
+
+// AsyncConfig configures asynchronous writing. Entries are queued and written by a background
+// goroutine per sink, Sync waits until the queued entries are written. The goroutines run until
+// the logger is closed, the logger must be closed once it is no longer used, see Close.
+type AsyncConfig struct {
+	// QueueSize is the max number of entries waiting to be written per sink, defaults to 1024
+	QueueSize int `json:"queue_size" yaml:"queue_size" env:"QUEUE_SIZE"`
+	// Overflow is the policy applied when the queue is full
+	Overflow OverflowPolicy `json:"overflow" yaml:"overflow" env:"OVERFLOW"`
+	// FlushInterval is the max time written entries stay buffered, defaults to one second
+	FlushInterval time.Duration `json:"flush_interval" yaml:"flush_interval" env:"FLUSH_INTERVAL"`
+	// BufferSize is the size of the write buffer in bytes, defaults to 256 KiB
+	BufferSize int `json:"buffer_size" yaml:"buffer_size" env:"BUFFER_SIZE"`
+}
+
+// asyncWriter queues writes and performs them on a background goroutine. Unbuffered writers
+// pass each write through on its own, which keeps datagram based writers one entry per packet.
+type asyncWriter struct {
+	ws       zapcore.WriteSyncer
+	buf      *bufio.Writer // nil when unbuffered
+	queue    chan []byte
+	syncs    chan chan error
+	done     chan struct{}
+	overflow OverflowPolicy
+	onDrop   func()
+	// errorOutput reports the failed writes of the background goroutine
+	errorOutput zapcore.WriteSyncer
+
+	// mu guards closed, Write holds it for reading until the entry is queued so Close can't
+	// drain the queue in between
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	stopped   chan struct{}
+}
+
+func newAsyncWriter(ws zapcore.WriteSyncer, config AsyncConfig, buffered bool, onDrop func()) *asyncWriter {
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultAsyncQueueSize
+	}
+	flushInterval := config.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultAsyncFlushInterval
+	}
+	bufferSize := config.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultAsyncBufferSize
+	}
+	if onDrop == nil {
+		onDrop = func() {}
+	}
+
+	w := &asyncWriter{
+		ws:          ws,
+		queue:       make(chan []byte, queueSize),
+		syncs:       make(chan chan error),
+		done:        make(chan struct{}),
+		overflow:    config.Overflow,
+		onDrop:      onDrop,
+		errorOutput: stderrOutput,
+		stopped:     make(chan struct{}),
+	}
+	if buffered {
+		w.buf = bufio.NewWriterSize(ws, bufferSize)
+	}
+	go w.run(flushInterval)
+	return w
+}
+
+// Write queues a copy of p, zap reuses its buffers once Write returns. It fails once the writer
+// is closed, nothing would write the queued entry.
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, errAsyncWriterClosed
+	}
+	entry := append([]byte(nil), p...)
+
+	switch w.overflow {
+	case OverflowDropNewest:
+		select {
+		case w.queue <- entry:
+		default:
+			w.onDrop()
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case w.queue <- entry:
+				return len(p), nil
+			default:
+			}
+			select {
+			case <-w.queue:
+				w.onDrop()
+			default:
+			}
+		}
+	default:
+		select {
+		case w.queue <- entry:
+		case <-w.stopped:
+			return 0, errAsyncWriterClosed
+		}
+	}
+	return len(p), nil
+}
+
+// Sync waits until the queued entries are written, then syncs the underlying writer.
+func (w *asyncWriter) Sync() error {
+	reply := make(chan error, 1)
+	select {
+	case w.syncs <- reply:
+		return <-reply
+	case <-w.stopped:
+		return nil
+	}
+}
+
+// Close writes the queued entries and stops the background goroutine.
+func (w *asyncWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		close(w.done)
+	})
+	<-w.stopped
+	return nil
+}
+
+func (w *asyncWriter) run(flushInterval time.Duration) {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry := <-w.queue:
+			w.write(entry)
+		case <-ticker.C:
+			if w.buf != nil {
+				w.report(w.flush())
+			}
+		case reply := <-w.syncs:
+			reply <- w.drain()
+		case <-w.done:
+			w.report(w.drain())
+			return
+		}
+	}
+}
+
+// drain writes the queued entries and flushes the buffer to the underlying writer.
+func (w *asyncWriter) drain() error {
+queue:
+	for {
+		select {
+		case entry := <-w.queue:
+			w.write(entry)
+		default:
+			break queue
+		}
+	}
+
+	if w.buf != nil {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	return w.ws.Sync()
+}
+
+func (w *asyncWriter) write(entry []byte) {
+	if w.buf != nil {
+		if _, err := w.buf.Write(entry); err != nil {
+			w.buf.Reset(w.ws)
+			w.report(err)
+		}
+		return
+	}
+	_, err := w.ws.Write(entry)
+	w.report(err)
+}
+
+// flush flushes the buffer. A failed flush discards the buffered entries, bufio would otherwise
+// keep failing every later write with the same error.
+func (w *asyncWriter) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		w.buf.Reset(w.ws)
+		return err
+	}
+	return nil
+}
+
+// report writes err to the error output, like zap does for the write errors of the cores.
+func (w *asyncWriter) report(err error) {
+	if err == nil {
+		return
+	}
+	fmt.Fprintf(w.errorOutput, "%v async write error: %v\n", time.Now(), err)
+	_ = w.errorOutput.Sync()
+}
@@ -0,0 +1,132 @@
+package logutil
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+// blockingWriter blocks writes until release is closed.
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) Sync() error { return nil }
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriterSync(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)},
+		Async: &AsyncConfig{FlushInterval: time.Hour},
+	})
+	require.Nil(t, err)
+
+	for i := 0; i < 100; i++ {
+		l.Infow("queued", "i", i)
+	}
+	require.Nil(t, l.Sync())
+	require.Len(t, decodeLines(t, &buf), 100)
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	tests := map[OverflowPolicy]string{
+		OverflowDropNewest: "0123",
+		OverflowDropOldest: "0789",
+	}
+	for policy, expected := range tests {
+		w := &blockingWriter{release: make(chan struct{})}
+		dropped := 0
+		aw := newAsyncWriter(zapcore.AddSync(w), AsyncConfig{QueueSize: 3, Overflow: policy}, false, func() { dropped++ })
+
+		// The first write is taken by the background goroutine and blocks it
+		_, _ = aw.Write([]byte("0"))
+		require.Eventually(t, func() bool { return len(aw.queue) == 0 }, time.Second, time.Millisecond)
+		for _, p := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"} {
+			_, _ = aw.Write([]byte(p))
+		}
+
+		close(w.release)
+		require.Nil(t, aw.Close())
+		require.Equal(t, expected, w.String(), policy.String())
+		require.Equal(t, 6, dropped, policy.String())
+	}
+}
+
+func TestAsyncWriterClosed(t *testing.T) {
+	var buf bytes.Buffer
+	aw := newAsyncWriter(zapcore.AddSync(&buf), AsyncConfig{Overflow: OverflowDropNewest}, false, nil)
+	require.Nil(t, aw.Close())
+
+	_, err := aw.Write([]byte("late\n"))
+	require.Equal(t, errAsyncWriterClosed, err)
+	require.Empty(t, buf.String())
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+func (failingWriter) Sync() error               { return nil }
+
+func TestAsyncWriterErrors(t *testing.T) {
+	for _, buffered := range []bool{false, true} {
+		var errorOutput bytes.Buffer
+		aw := newAsyncWriter(failingWriter{}, AsyncConfig{}, buffered, nil)
+		aw.errorOutput = zapcore.AddSync(&errorOutput)
+
+		_, err := aw.Write([]byte("lost\n"))
+		require.Nil(t, err)
+		require.Nil(t, aw.Close())
+		require.Contains(t, errorOutput.String(), "async write error: disk full")
+	}
+}
+
+func TestAsyncWriterConcurrentClose(t *testing.T) {
+	var buf bytes.Buffer
+	aw := newAsyncWriter(zapcore.AddSync(&buf), AsyncConfig{}, true, nil)
+
+	var wg sync.WaitGroup
+	written := make([]int, 8)
+	for i := range written {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				if _, err := aw.Write([]byte("entry\n")); err != nil {
+					return
+				}
+				written[i]++
+			}
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	require.Nil(t, aw.Close())
+	wg.Wait()
+
+	// Every accepted write is written by the final drain
+	total := 0
+	for _, n := range written {
+		total += n
+	}
+	require.Equal(t, total, bytes.Count(buf.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,90 @@
+package logutil
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// nextLine returns the line number following its caller.
+func nextLine() string {
+	_, _, l, _ := runtime.Caller(1)
+	return strconv.Itoa(l + 1)
+}
+
+func TestCaller(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		CallerEnabled: true,
+		Sinks:         []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)},
+	})
+	require.Nil(t, err)
+
+	defaultLogger := DefaultLogger
+	DefaultLogger = l
+	defer func() { DefaultLogger = defaultLogger }()
+
+	var lines []string
+	lines = append(lines, nextLine())
+	l.Info("method")
+	lines = append(lines, nextLine())
+	l.InfoCtx(context.Background(), "ctx method")
+	lines = append(lines, nextLine())
+	l.With("k", "v").Named("child").Infow("child method")
+	lines = append(lines, nextLine())
+	Info("helper")
+	lines = append(lines, nextLine())
+	InfoCtx(context.Background(), "ctx helper")
+	lines = append(lines, nextLine())
+	Named("child").Info("child of helper")
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, len(lines))
+	for i, entry := range entries {
+		require.Equal(t, "logutil/caller_test.go:"+lines[i], entry["caller"], entry["msg"])
+	}
+}
+
+// mapLogger is a Logger of another package, not comparable because of its map.
+type mapLogger struct {
+	Logger
+	tags map[string]string
+}
+
+func TestHelperWithUncomparableLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)},
+	})
+	require.Nil(t, err)
+
+	defaultLogger := DefaultLogger
+	DefaultLogger = mapLogger{Logger: l, tags: map[string]string{}}
+	defer func() { DefaultLogger = defaultLogger }()
+
+	Info("first")
+	Info("second")
+	require.Len(t, decodeLines(t, &buf), 2)
+}
+
+func TestStacktrace(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		StacktraceEnabled: true,
+		StacktraceLevel:   ErrorLevel,
+		Sinks:             []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)},
+	})
+	require.Nil(t, err)
+
+	l.Warn("no stack")
+	l.Error("stack")
+
+	entries := decodeLines(t, &buf)
+	require.NotContains(t, entries[0], "stacktrace")
+	require.Contains(t, entries[1]["stacktrace"], "logutil.TestStacktrace")
+	require.NotContains(t, entries[1]["stacktrace"], "zap.(*SugaredLogger)")
+}
@@ -0,0 +1,259 @@
+package logutil
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// LoggerConfigFromEnv returns a validated LoggerConfig loaded from the environment variables with
+// the given prefix. See LoadEnv for the variable names.
+func LoggerConfigFromEnv(prefix string) (LoggerConfig, error) {
+	var config LoggerConfig
+	if err := config.LoadEnv(prefix); err != nil {
+		return config, err
+	}
+	return config, config.Validate()
+}
+
+// LoadEnv overrides the fields of the config with the environment variables with the given prefix.
+// Variables are named after the prefix and the field, e.g. APP_CONSOLE_LEVEL or APP_MAX_SIZE for
+// the prefix "APP". Unset variables leave the fields untouched.
+//
+// Levels are given by name, booleans as accepted by strconv.ParseBool, durations as accepted by
+// time.ParseDuration and lists are comma-separated.
+func (c *LoggerConfig) LoadEnv(prefix string) error {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	return loadEnv(reflect.ValueOf(c).Elem(), prefix)
+}
+
+// LoggerConfigFromJSON returns a validated LoggerConfig unmarshalled from JSON. Unknown fields are
+// rejected. Durations are given as strings accepted by time.ParseDuration, e.g. "1s", or as
+// nanoseconds.
+func LoggerConfigFromJSON(data []byte) (LoggerConfig, error) {
+	var config LoggerConfig
+	data, err := jsonDurations(data, reflect.TypeOf(config))
+	if err != nil {
+		return config, fmt.Errorf("could not decode logger config. err: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("could not decode logger config. err: %v", err)
+	}
+	return config, config.Validate()
+}
+
+// LoggerConfigFromYAML returns a validated LoggerConfig unmarshalled from YAML. Unknown fields are
+// rejected.
+func LoggerConfigFromYAML(data []byte) (LoggerConfig, error) {
+	var config LoggerConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// An empty document leaves the config untouched
+	if err := decoder.Decode(&config); err != nil && len(bytes.TrimSpace(data)) > 0 {
+		return config, fmt.Errorf("could not decode logger config. err: %v", err)
+	}
+	return config, config.Validate()
+}
+
+// LoadLoggerConfig reads the LoggerConfig from the given file. Files with a .json extension
+// are decoded as JSON, any other file as YAML.
+func LoadLoggerConfig(path string) (LoggerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LoggerConfig{}, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return LoggerConfigFromJSON(data)
+	}
+	return LoggerConfigFromYAML(data)
+}
+
+// jsonDurations replaces the duration strings of the JSON object data, decoded into the struct type
+// t, by their nanoseconds. Nested configs are handled the same way.
+func jsonDurations(data []byte, t reflect.Type) ([]byte, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		// Not an object, the decoder reports it
+		return data, nil
+	}
+
+	changed := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		if isNestedConfig(fieldType) && fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		// Keys are matched like encoding/json does, case-insensitively
+		for key, raw := range object {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			var value []byte
+			switch {
+			case fieldType == durationType:
+				var s string
+				if json.Unmarshal(raw, &s) != nil {
+					continue
+				}
+				d, err := time.ParseDuration(s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q for %s: %v", s, name, err)
+				}
+				value = []byte(strconv.FormatInt(int64(d), 10))
+			case isNestedConfig(fieldType):
+				var err error
+				if value, err = jsonDurations(raw, fieldType); err != nil {
+					return nil, err
+				}
+			default:
+				continue
+			}
+			object[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return data, nil
+	}
+	return json.Marshal(object)
+}
+
+// loadEnv sets the fields of the struct v which have an env tag from the environment.
+func loadEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("env")
+		if tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+		name := prefix + tag
+		fv := v.Field(i)
+
+		// Nested configs use their tag as a prefix of their own fields
+		if isNestedConfig(fv.Type()) {
+			if fv.Kind() == reflect.Ptr {
+				if !hasEnvPrefix(name + "_") {
+					continue
+				}
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if err := loadEnv(fv, name+"_"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(fv, val); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %v", val, name, err)
+		}
+	}
+	return nil
+}
+
+func isNestedConfig(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType) && t != reflect.TypeOf(time.Time{})
+}
+
+func hasEnvPrefix(prefix string) bool {
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func setEnvValue(v reflect.Value, val string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if val != "" {
+			parts = strings.Split(val, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setEnvValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
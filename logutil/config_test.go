@@ -0,0 +1,132 @@
+package logutil
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]LogLevel{
+		"debug": DebugLevel,
+		"INFO":  InfoLevel,
+		"Warn":  WarnLevel,
+		"error": ErrorLevel,
+		"panic": PanicLevel,
+		"fatal": FatalLevel,
+	}
+	for text, expected := range tests {
+		level, err := ParseLogLevel(text)
+		require.Nil(t, err)
+		require.Equal(t, expected, level)
+	}
+
+	_, err := ParseLogLevel("loud")
+	require.NotNil(t, err)
+}
+
+func TestLogLevelText(t *testing.T) {
+	data, err := json.Marshal(map[string]LogLevel{"level": WarnLevel})
+	require.Nil(t, err)
+	require.Equal(t, `{"level":"warn"}`, string(data))
+
+	_, err = LogLevel(42).MarshalText()
+	require.NotNil(t, err)
+}
+
+func TestLoggerConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_NAME", "my app")
+	t.Setenv("APP_CONSOLE_ENABLED", "true")
+	t.Setenv("APP_CONSOLE_LEVEL", "debug")
+	t.Setenv("APP_MAX_SIZE", "10")
+
+	config, err := LoggerConfigFromEnv("APP")
+	require.Nil(t, err)
+	require.Equal(t, "my app", config.Name)
+	require.True(t, config.ConsoleEnabled)
+	require.Equal(t, DebugLevel, config.ConsoleLevel)
+	require.Equal(t, 10, config.MaxSize)
+
+	t.Setenv("APP_MAX_AGE", "ten")
+	_, err = LoggerConfigFromEnv("APP")
+	require.ErrorContains(t, err, "APP_MAX_AGE")
+}
+
+func TestLoadLoggerConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "log.json")
+	require.Nil(t, os.WriteFile(jsonPath, []byte(`{"file_enabled": true, "file_level": "warn", "log_directory": "logs", "filename": "app.log",
+		"drop_report_interval": "1m", "async": {"flush_interval": "1s"}, "sampling": {"tick": 1000}}`), 0644))
+	config, err := LoadLoggerConfig(jsonPath)
+	require.Nil(t, err)
+	require.True(t, config.FileEnabled)
+	require.Equal(t, WarnLevel, config.FileLevel)
+	require.Equal(t, "app.log", config.Filename)
+	require.Equal(t, time.Minute, config.DropReportInterval)
+	require.Equal(t, time.Second, config.Async.FlushInterval)
+	require.Equal(t, time.Microsecond, config.Sampling.Tick)
+
+	yamlPath := filepath.Join(dir, "log.yaml")
+	require.Nil(t, os.WriteFile(yamlPath, []byte("console_level: error\nmax_backups: 3\n"), 0644))
+	config, err = LoadLoggerConfig(yamlPath)
+	require.Nil(t, err)
+	require.Equal(t, ErrorLevel, config.ConsoleLevel)
+	require.Equal(t, 3, config.MaxBackup)
+
+	_, err = LoggerConfigFromYAML([]byte("console_level: loud\n"))
+	require.NotNil(t, err)
+	_, err = LoggerConfigFromJSON([]byte(`{"consol_level": "info"}`))
+	require.NotNil(t, err)
+	_, err = LoggerConfigFromJSON([]byte(`{"async": {"flush_interval": "soon"}}`))
+	require.ErrorContains(t, err, "flush_interval")
+
+	// Decoded configs are validated
+	_, err = LoggerConfigFromJSON([]byte(`{"file_enabled": true, "filename": "app.log"}`))
+	require.ErrorContains(t, err, "log directory is required")
+	_, err = LoggerConfigFromYAML([]byte("max_size: -1\n"))
+	require.ErrorContains(t, err, "max size must not be negative")
+	t.Setenv("APP_MAX_BACKUPS", "-1")
+	_, err = LoggerConfigFromEnv("APP")
+	require.ErrorContains(t, err, "max backups must not be negative")
+}
+
+func TestValidate(t *testing.T) {
+	require.Nil(t, LoggerConfig{ConsoleEnabled: true}.Validate())
+
+	err := LoggerConfig{
+		ConsoleLevel: LogLevel(9),
+		FileEnabled:  true,
+		Filename:     "logs/app.log",
+		MaxSize:      -1,
+	}.Validate()
+	require.ErrorContains(t, err, "invalid console level")
+	require.ErrorContains(t, err, "log directory is required")
+	require.ErrorContains(t, err, "filename must not contain a directory")
+	require.ErrorContains(t, err, "max size must not be negative")
+}
+
+func TestNewLoggerE(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := NewLoggerE(LoggerConfig{FileEnabled: true, LogDirectory: dir, Filename: "app.log"})
+	require.Nil(t, err)
+	l.Info("hello")
+	require.Nil(t, l.Sync())
+	require.FileExists(t, filepath.Join(dir, "app.log"))
+
+	_, err = NewLoggerE(LoggerConfig{FileEnabled: true, LogDirectory: dir})
+	require.ErrorContains(t, err, "filename is required")
+
+	// A file in place of the directory can't be created
+	notDir := filepath.Join(dir, "app.log")
+	_, err = NewLoggerE(LoggerConfig{FileEnabled: true, LogDirectory: notDir, Filename: "app.log"})
+	require.ErrorContains(t, err, "could not create log directory")
+
+	// NewLogger keeps working without the file output
+	require.NotNil(t, NewLogger(LoggerConfig{FileEnabled: true, LogDirectory: notDir, Filename: "app.log"}))
+}
@@ -0,0 +1,75 @@
+package logutil
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextExtractor returns the key-value pairs found in ctx that should be attached to log entries.
+// The returned pairs are treated as they are in Infow.
+type ContextExtractor func(ctx context.Context) []interface{}
+
+type contextFieldsKey struct{}
+
+var (
+	contextExtractorsMu sync.RWMutex
+	contextExtractors   []ContextExtractor
+)
+
+// RegisterContextExtractor adds an extractor which is consulted by every context-aware logging call,
+// such as WithContext and InfoCtx.
+func RegisterContextExtractor(extractor ContextExtractor) {
+	if extractor == nil {
+		return
+	}
+	contextExtractorsMu.Lock()
+	defer contextExtractorsMu.Unlock()
+	contextExtractors = append(contextExtractors, extractor)
+}
+
+// RegisterContextKey attaches the value stored in the context under key to log entries as field.
+// Nothing is attached when the context doesn't carry the key.
+func RegisterContextKey(key interface{}, field string) {
+	RegisterContextExtractor(func(ctx context.Context) []interface{} {
+		if val := ctx.Value(key); val != nil {
+			return []interface{}{field, val}
+		}
+		return nil
+	})
+}
+
+// ResetContextExtractors removes all registered extractors.
+func ResetContextExtractors() {
+	contextExtractorsMu.Lock()
+	defer contextExtractorsMu.Unlock()
+	contextExtractors = nil
+}
+
+// ContextWithFields returns a copy of ctx carrying the given key-value pairs. The pairs are
+// attached to log entries of every context-aware logging call receiving the returned context.
+func ContextWithFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	fields := append(contextFieldsFrom(ctx), keysAndValues...)
+	return context.WithValue(ctx, contextFieldsKey{}, fields)
+}
+
+func contextFieldsFrom(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(contextFieldsKey{}).([]interface{})
+	// Return a copy so appending doesn't mutate the parent's fields
+	return append([]interface{}(nil), fields...)
+}
+
+// contextFields collects the key-value pairs of ctx from ContextWithFields and the registered extractors.
+func contextFields(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+
+	fields := contextFieldsFrom(ctx)
+
+	contextExtractorsMu.RLock()
+	defer contextExtractorsMu.RUnlock()
+	for _, extractor := range contextExtractors {
+		fields = append(fields, extractor(ctx)...)
+	}
+	return fields
+}
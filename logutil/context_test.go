@@ -0,0 +1,36 @@
+package logutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCtxKey string
+
+func TestContextFields(t *testing.T) {
+	defer ResetContextExtractors()
+
+	RegisterContextKey(testCtxKey("request_id"), "request_id")
+	RegisterContextExtractor(func(ctx context.Context) []interface{} {
+		return []interface{}{"tenant", "acme"}
+	})
+
+	ctx := context.WithValue(context.Background(), testCtxKey("request_id"), "abc")
+	ctx = ContextWithFields(ctx, "trace_id", "t1")
+	require.Equal(t, []interface{}{"trace_id", "t1", "request_id", "abc", "tenant", "acme"}, contextFields(ctx))
+
+	// Keys missing from the context are skipped
+	require.Equal(t, []interface{}{"tenant", "acme"}, contextFields(context.Background()))
+}
+
+func TestContextWithFieldsDoesNotMutateParent(t *testing.T) {
+	parent := ContextWithFields(context.Background(), "a", 1)
+	child1 := ContextWithFields(parent, "b", 2)
+	child2 := ContextWithFields(parent, "c", 3)
+
+	require.Equal(t, []interface{}{"a", 1}, contextFieldsFrom(parent))
+	require.Equal(t, []interface{}{"a", 1, "b", 2}, contextFieldsFrom(child1))
+	require.Equal(t, []interface{}{"a", 1, "c", 3}, contextFieldsFrom(child2))
+}
@@ -1,32 +1,64 @@
 package logutil
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
 var (
 	DefaultLogger Logger
 )
 
+// helperCache holds the variant of the DefaultLogger used by the package-level logging helpers.
+type helperCache struct {
+	base    *logger
+	skipped Logger
+}
+
+var defaultHelper atomic.Pointer[helperCache]
+
+// helperLogger returns the DefaultLogger adjusted to report the callers of the package-level
+// helpers rather than the helpers themselves. Loggers of other packages are returned as is, they
+// can't be adjusted and may not be comparable.
+func helperLogger() Logger {
+	l, ok := DefaultLogger.(*logger)
+	if !ok {
+		return DefaultLogger
+	}
+	if cache := defaultHelper.Load(); cache != nil && cache.base == l {
+		return cache.skipped
+	}
+
+	skipped := l.withCallerSkip(1)
+	defaultHelper.Store(&helperCache{base: l, skipped: skipped})
+	return skipped
+}
+
 func init() {
 	DefaultLogger = NewLogger(LoggerConfig{
-		ConsoleEnabled: true,
-		ConsoleLevel:   InfoLevel,
-		ConsoleJson:    false,
-		FileEnabled:    false,
-		FileJson:       true,
+		ConsoleEnabled:  true,
+		ConsoleLevel:    InfoLevel,
+		ConsoleEncoding: TextEncoding,
+		FileEnabled:     false,
+		FileEncoding:    JSONEncoding,
 	})
 }
 
 // Debug logs the provided arguments at [DebugLevel]. Spaces are added between arguments when neither is a string.
 func Debug(args ...interface{}) {
-	DefaultLogger.Debug(args...)
+	helperLogger().Debug(args...)
 }
 
 // Debugf formats the message according to the format specifier and logs it at [DebugLevel].
 func Debugf(template string, args ...interface{}) {
-	DefaultLogger.Debugf(template, args...)
+	helperLogger().Debugf(template, args...)
 }
 
 // Debugln logs a message at [DebugLevel]. Spaces are always added between arguments.
 func Debugln(args ...interface{}) {
-	DefaultLogger.Debugln(args...)
+	helperLogger().Debugln(args...)
 }
 
 // Debugw logs a message with some additional context. The variadic key-value pairs are treated as they are in With.
@@ -35,122 +67,167 @@ func Debugln(args ...interface{}) {
 //
 //	s.With(keysAndValues).Debug(msg)
 func Debugw(msg string, keysAndValues ...interface{}) {
-	DefaultLogger.Debugw(msg, keysAndValues...)
+	helperLogger().Debugw(msg, keysAndValues...)
 }
 
 // Info logs the provided arguments at [InfoLevel]. Spaces are added between arguments when neither is a string.
 func Info(args ...interface{}) {
-	DefaultLogger.Info(args...)
+	helperLogger().Info(args...)
 }
 
 // Infof formats the message according to the format specifier and logs it at [InfoLevel].
 func Infof(template string, args ...interface{}) {
-	DefaultLogger.Infof(template, args...)
+	helperLogger().Infof(template, args...)
 }
 
 // Infoln logs a message at [InfoLevel]. Spaces are always added between arguments.
 func Infoln(args ...interface{}) {
-	DefaultLogger.Infoln(args...)
+	helperLogger().Infoln(args...)
 }
 
 // Infow logs a message with some additional context. The variadic key-value pairs are treated as they are in With.
 func Infow(msg string, keysAndValues ...interface{}) {
-	DefaultLogger.Infow(msg, keysAndValues...)
+	helperLogger().Infow(msg, keysAndValues...)
 }
 
 // Warn logs the provided arguments at [WarnLevel]. Spaces are added between arguments when neither is a string.
 func Warn(args ...interface{}) {
-	DefaultLogger.Warn(args...)
+	helperLogger().Warn(args...)
 }
 
 // Warnf formats the message according to the format specifier
 // and logs it at [WarnLevel].
 func Warnf(template string, args ...interface{}) {
-	DefaultLogger.Warnf(template, args...)
+	helperLogger().Warnf(template, args...)
 }
 
 // Warnln logs a message at [WarnLevel].
 // Spaces are always added between arguments.
 func Warnln(args ...interface{}) {
-	DefaultLogger.Warnln(args...)
+	helperLogger().Warnln(args...)
 }
 
 // Warnw logs a message with some additional context. The variadic key-value
 // pairs are treated as they are in With.
 func Warnw(msg string, keysAndValues ...interface{}) {
-	DefaultLogger.Warnw(msg, keysAndValues...)
+	helperLogger().Warnw(msg, keysAndValues...)
 }
 
 // Error logs the provided arguments at [ErrorLevel].
 // Spaces are added between arguments when neither is a string.
 func Error(args ...interface{}) {
-	DefaultLogger.Error(args...)
+	helperLogger().Error(args...)
 }
 
 // Errorf formats the message according to the format specifier
 // and logs it at [ErrorLevel].
 func Errorf(template string, args ...interface{}) {
-	DefaultLogger.Errorf(template, args...)
+	helperLogger().Errorf(template, args...)
 }
 
 // Errorln logs a message at [ErrorLevel].
 // Spaces are always added between arguments.
 func Errorln(args ...interface{}) {
-	DefaultLogger.Errorln(args...)
+	helperLogger().Errorln(args...)
 }
 
 // Errorw logs a message with some additional context. The variadic key-value
 // pairs are treated as they are in With.
 func Errorw(msg string, keysAndValues ...interface{}) {
-	DefaultLogger.Errorw(msg, keysAndValues...)
+	helperLogger().Errorw(msg, keysAndValues...)
 }
 
 // Panic constructs a message with the provided arguments and panics.
 // Spaces are added between arguments when neither is a string.
 func Panic(args ...interface{}) {
-	DefaultLogger.Panic(args...)
+	helperLogger().Panic(args...)
 }
 
 // Panicf formats the message according to the format specifier
 // and panics.
 func Panicf(template string, args ...interface{}) {
-	DefaultLogger.Panicf(template, args...)
+	helperLogger().Panicf(template, args...)
 }
 
 // Panicln logs a message at [PanicLevel] and panics.
 // Spaces are always added between arguments.
 func Panicln(args ...interface{}) {
-	DefaultLogger.Panicln(args...)
+	helperLogger().Panicln(args...)
 }
 
 // Panicw logs a message with some additional context, then panics. The
 // variadic key-value pairs are treated as they are in With.
 func Panicw(msg string, keysAndValues ...interface{}) {
-	DefaultLogger.Panicw(msg, keysAndValues...)
+	helperLogger().Panicw(msg, keysAndValues...)
 }
 
 // Fatal constructs a message with the provided arguments and calls os.Exit.
 // Spaces are added between arguments when neither is a string.
 func Fatal(args ...interface{}) {
-	DefaultLogger.Fatal(args...)
+	helperLogger().Fatal(args...)
 }
 
 // Fatalf formats the message according to the format specifier
 // and calls os.Exit.
 func Fatalf(template string, args ...interface{}) {
-	DefaultLogger.Fatalf(template, args...)
+	helperLogger().Fatalf(template, args...)
 }
 
 // Fatalln logs a message at [FatalLevel] and calls os.Exit.
 // Spaces are always added between arguments.
 func Fatalln(args ...interface{}) {
-	DefaultLogger.Fatalln(args...)
+	helperLogger().Fatalln(args...)
 }
 
 // Fatalw logs a message with some additional context, then calls os.Exit. The
 // variadic key-value pairs are treated as they are in With.
 func Fatalw(msg string, keysAndValues ...interface{}) {
-	DefaultLogger.Fatalw(msg, keysAndValues...)
+	helperLogger().Fatalw(msg, keysAndValues...)
+}
+
+// With returns a child of the DefaultLogger carrying the given key-value pairs on every entry.
+func With(keysAndValues ...interface{}) Logger {
+	return DefaultLogger.With(keysAndValues...)
+}
+
+// WithFields returns a child of the DefaultLogger carrying the given strongly typed fields on every entry.
+func WithFields(fields ...Field) Logger {
+	return DefaultLogger.WithFields(fields...)
+}
+
+// WithContext returns a child of the DefaultLogger carrying the fields extracted from ctx.
+func WithContext(ctx context.Context) Logger {
+	return DefaultLogger.WithContext(ctx)
+}
+
+// DebugCtx logs a message at [DebugLevel] with the fields extracted from ctx and some additional context.
+func DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	helperLogger().DebugCtx(ctx, msg, keysAndValues...)
+}
+
+// InfoCtx logs a message at [InfoLevel] with the fields extracted from ctx and some additional context.
+func InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	helperLogger().InfoCtx(ctx, msg, keysAndValues...)
+}
+
+// WarnCtx logs a message at [WarnLevel] with the fields extracted from ctx and some additional context.
+func WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	helperLogger().WarnCtx(ctx, msg, keysAndValues...)
+}
+
+// ErrorCtx logs a message at [ErrorLevel] with the fields extracted from ctx and some additional context.
+func ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	helperLogger().ErrorCtx(ctx, msg, keysAndValues...)
+}
+
+// PanicCtx logs a message with the fields extracted from ctx and some additional context, then panics.
+func PanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	helperLogger().PanicCtx(ctx, msg, keysAndValues...)
+}
+
+// FatalCtx logs a message with the fields extracted from ctx and some additional context, then calls os.Exit.
+func FatalCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	helperLogger().FatalCtx(ctx, msg, keysAndValues...)
 }
 
 // Named adds a new path segment to the logger's name. Segments are joined by
@@ -165,6 +242,25 @@ func Sync() error {
 	return DefaultLogger.Sync()
 }
 
+// Rotate rotates the log file of the DefaultLogger, it does nothing when the DefaultLogger is not a Rotator
+func Rotate() error {
+	if l, ok := DefaultLogger.(Rotator); ok {
+		return l.Rotate()
+	}
+	return nil
+}
+
+// Close closes the DefaultLogger, entries logged afterwards are discarded. The loggers built by
+// NewLogger and NewLoggerE implement io.Closer and must be closed once they are no longer used,
+// which releases the log file, the background goroutines of async writing and drop reports, and
+// the connections of network and syslog sinks. It does nothing for other loggers.
+func Close() error {
+	if l, ok := DefaultLogger.(io.Closer); ok {
+		return l.Close()
+	}
+	return nil
+}
+
 // SetConsoleLevel sets the console log level
 func SetConsoleLevel(level LogLevel) {
 	DefaultLogger.SetConsoleLevel(level)
@@ -174,3 +270,76 @@ func SetConsoleLevel(level LogLevel) {
 func SetFileLevel(level LogLevel) {
 	DefaultLogger.SetFileLevel(level)
 }
+
+// ConsoleLevel returns the console log level, InfoLevel when the DefaultLogger is not a LevelReporter
+func ConsoleLevel() LogLevel {
+	if l, ok := DefaultLogger.(LevelReporter); ok {
+		return l.ConsoleLevel()
+	}
+	return InfoLevel
+}
+
+// FileLevel returns the file log level, InfoLevel when the DefaultLogger is not a LevelReporter
+func FileLevel() LogLevel {
+	if l, ok := DefaultLogger.(LevelReporter); ok {
+		return l.FileLevel()
+	}
+	return InfoLevel
+}
+
+// SetSinkLevel sets the log level of the sink with the given name
+func SetSinkLevel(name string, level LogLevel) error {
+	l, ok := DefaultLogger.(SinkLeveler)
+	if !ok {
+		return fmt.Errorf("logger does not support sink levels")
+	}
+	return l.SetSinkLevel(name, level)
+}
+
+// SinkLevel returns the log level of the sink with the given name
+func SinkLevel(name string) (LogLevel, bool) {
+	if l, ok := DefaultLogger.(SinkLeveler); ok {
+		return l.SinkLevel(name)
+	}
+	return 0, false
+}
+
+// SetNamedLevel overrides the sink levels for the named logger and its descendants. It does nothing
+// when the DefaultLogger is not a NamedLeveler.
+func SetNamedLevel(name string, level LogLevel) {
+	if l, ok := DefaultLogger.(NamedLeveler); ok {
+		l.SetNamedLevel(name, level)
+	}
+}
+
+// UnsetNamedLevel removes the level override registered for the given name
+func UnsetNamedLevel(name string) {
+	if l, ok := DefaultLogger.(NamedLeveler); ok {
+		l.UnsetNamedLevel(name)
+	}
+}
+
+// NamedLevel returns the level override in effect for the logger with the given name
+func NamedLevel(name string) (LogLevel, bool) {
+	if l, ok := DefaultLogger.(NamedLeveler); ok {
+		return l.NamedLevel(name)
+	}
+	return 0, false
+}
+
+// NamedLevels returns all registered level overrides keyed by logger name
+func NamedLevels() map[string]LogLevel {
+	if l, ok := DefaultLogger.(NamedLeveler); ok {
+		return l.NamedLevels()
+	}
+	return nil
+}
+
+// ApplyConfig applies config to the DefaultLogger and all loggers derived from it
+func ApplyConfig(config LoggerConfig) error {
+	l, ok := DefaultLogger.(ConfigApplier)
+	if !ok {
+		return fmt.Errorf("logger does not support config reload")
+	}
+	return l.ApplyConfig(config)
+}
@@ -0,0 +1,113 @@
+package logutil
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Encoding selects how entries are encoded by an output.
+type Encoding uint8
+
+const (
+	// TextEncoding is the human readable, tab separated encoding.
+	TextEncoding Encoding = iota
+	// JSONEncoding encodes each entry as a JSON object.
+	JSONEncoding
+	// LogfmtEncoding encodes each entry as a line of space separated key=value pairs.
+	LogfmtEncoding
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case TextEncoding:
+		return "text"
+	case JSONEncoding:
+		return "json"
+	case LogfmtEncoding:
+		return "logfmt"
+	default:
+		return fmt.Sprintf("Encoding(%d)", e)
+	}
+}
+
+// MarshalText marshals the Encoding to its name.
+func (e Encoding) MarshalText() ([]byte, error) {
+	if e > LogfmtEncoding {
+		return nil, fmt.Errorf("unknown encoding: %d", e)
+	}
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText unmarshals a case-insensitive encoding name such as "json" to an Encoding.
+func (e *Encoding) UnmarshalText(text []byte) error {
+	for enc := TextEncoding; enc <= LogfmtEncoding; enc++ {
+		if strings.EqualFold(string(text), enc.String()) {
+			*e = enc
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown encoding: %q", text)
+}
+
+// newEncoder returns the zap encoder for the encoding. Color only applies to the TextEncoding.
+func newEncoder(encoding Encoding, color bool, opts timeOptions) zapcore.Encoder {
+	return newEncoderWithConfig(encoding, encoderConfig(encoding, color, opts))
+}
+
+func newEncoderWithConfig(encoding Encoding, config zapcore.EncoderConfig) zapcore.Encoder {
+	switch encoding {
+	case JSONEncoding:
+		return zapcore.NewJSONEncoder(config)
+	case LogfmtEncoding:
+		return newLogfmtEncoder(config)
+	default:
+		return zapcore.NewConsoleEncoder(config)
+	}
+}
+
+// encoderConfig returns the zap encoder config for the encoding.
+func encoderConfig(encoding Encoding, color bool, opts timeOptions) zapcore.EncoderConfig {
+	// Prepare encoder configs
+	consoleEncoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.NanosDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	jsonEncoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.NanosDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	opts.apply(&consoleEncoderConfig)
+	opts.apply(&jsonEncoderConfig)
+
+	switch encoding {
+	case JSONEncoding, LogfmtEncoding:
+		return jsonEncoderConfig
+	default:
+		if color {
+			consoleEncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		} else {
+			consoleEncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		}
+		return consoleEncoderConfig
+	}
+}
@@ -0,0 +1,234 @@
+package logutil
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// maxErrorChain bounds the errors recorded by Err, guarding against cyclic chains.
+	maxErrorChain = 32
+	maxStackDepth = 32
+)
+
+// Err constructs a field with the key "error" recording err along with its chain of wrapped errors.
+//
+// Example:
+//
+//	{
+//		"message": "load config: open app.yaml: no such file or directory",
+//		"type": "*fmt.wrapError",
+//		"chain": [
+//			{"message": "load config: open app.yaml: no such file or directory", "type": "*fmt.wrapError"},
+//			{"message": "open app.yaml: no such file or directory", "type": "*fs.PathError"},
+//			{"message": "no such file or directory", "type": "syscall.Errno"}
+//		],
+//		"stack": "main.load\n\t/app/main.go:12\n..."
+//	}
+//
+// The chain follows errors.Unwrap, errors joined by errors.Join or wrapping several errors record
+// the chains of these errors under "errors". The stack is the first stack trace found in the chain,
+// e.g. from WithStack or a StackTrace method as in github.com/pkg/errors.
+func Err(err error) Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Object("error", errorObject{err: err})
+}
+
+// errorObject encodes an error with its chain and stack trace.
+type errorObject struct {
+	err error
+}
+
+func (o errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	message, err := errorMessage(o.err)
+	if err != nil {
+		return err
+	}
+	enc.AddString("message", message)
+	enc.AddString("type", fmt.Sprintf("%T", o.err))
+	budget := maxErrorChain
+	if err := enc.AddArray("chain", errorChain{err: o.err, budget: &budget}); err != nil {
+		return err
+	}
+	if stack := findStack(o.err); stack != "" {
+		enc.AddString("stack", stack)
+	}
+	return nil
+}
+
+// errorChain encodes the errors unwrapped from err, err included.
+type errorChain struct {
+	err    error
+	budget *int // errors left to encode, shared by the nested chains
+}
+
+func (c errorChain) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for err := c.err; err != nil && *c.budget > 0; {
+		*c.budget--
+		if encErr := enc.AppendObject(errorNode{err: err, budget: c.budget}); encErr != nil {
+			return encErr
+		}
+
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			if isNilPointer(err) {
+				return nil
+			}
+			err = u.Unwrap()
+		default:
+			// Errors wrapping several errors end the chain, their node holds the nested chains
+			err = nil
+		}
+	}
+	return nil
+}
+
+// errorNode encodes an error of a chain.
+type errorNode struct {
+	err    error
+	budget *int
+}
+
+func (n errorNode) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	message, err := errorMessage(n.err)
+	if err != nil {
+		return err
+	}
+	enc.AddString("message", message)
+	enc.AddString("type", fmt.Sprintf("%T", n.err))
+	if u, ok := n.err.(interface{ Unwrap() []error }); ok && !isNilPointer(n.err) {
+		return enc.AddArray("errors", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+			for _, err := range u.Unwrap() {
+				if err == nil {
+					continue
+				}
+				if err := enc.AppendArray(errorChain{err: err, budget: n.budget}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+	}
+	return nil
+}
+
+// findStack returns the first stack trace attached to an error of the chain of err.
+func findStack(err error) string {
+	budget := maxErrorChain
+	var find func(err error) string
+	find = func(err error) string {
+		for ; err != nil && budget > 0; budget-- {
+			if stack := errorStack(err); stack != "" {
+				return stack
+			}
+			if isNilPointer(err) {
+				return ""
+			}
+			switch u := err.(type) {
+			case interface{ Unwrap() error }:
+				err = u.Unwrap()
+			case interface{ Unwrap() []error }:
+				for _, err := range u.Unwrap() {
+					if stack := find(err); stack != "" {
+						return stack
+					}
+				}
+				return ""
+			default:
+				return ""
+			}
+		}
+		return ""
+	}
+	return find(err)
+}
+
+// errorMessage returns the message of err like zap.Error, nil pointers whose Error method panics
+// are "<nil>" and other panics are returned as errors.
+func errorMessage(err error) (message string, retErr error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if isNilPointer(err) {
+				message = "<nil>"
+				return
+			}
+			retErr = fmt.Errorf("PANIC=%v", rec)
+		}
+	}()
+	return err.Error(), nil
+}
+
+// isNilPointer reports whether err is a nil pointer, whose methods may panic.
+func isNilPointer(err error) bool {
+	v := reflect.ValueOf(err)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// errorStack returns the stack trace of err when it has a StackTrace method, whatever its result type.
+// StackTrace methods which panic have no stack trace.
+func errorStack(err error) (stack string) {
+	defer func() {
+		if recover() != nil {
+			stack = ""
+		}
+	}()
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%+v", method.Call(nil)[0].Interface()))
+}
+
+// stackError attaches the stack trace of its creation to an error.
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+// WithStack returns err annotated with the stack trace of the WithStack call, recorded by Err.
+// Errors already carrying a stack trace are returned as they are.
+func WithStack(err error) error {
+	if err == nil || findStack(err) != "" {
+		return err
+	}
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: err, pcs: pcs[:n]}
+}
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stackError) Unwrap() error {
+	return e.err
+}
+
+// StackTrace formats the stack trace like the stack traces of the entries.
+func (e *stackError) StackTrace() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(e.pcs)
+	for {
+		frame, more := frames.Next()
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
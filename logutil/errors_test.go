@@ -0,0 +1,110 @@
+package logutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type tracedError struct{}
+
+func (tracedError) Error() string { return "traced" }
+
+func (tracedError) StackTrace() []string { return []string{"pkg.fn", "pkg.caller"} }
+
+type pointerError struct {
+	msg string
+}
+
+func (e *pointerError) Error() string { return e.msg }
+
+func (e *pointerError) StackTrace() string { return e.msg }
+
+type panicError struct{}
+
+func (panicError) Error() string { panic("no message") }
+
+func TestErr(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)}})
+	require.Nil(t, err)
+
+	_, openErr := os.Open("/does/not/exist")
+	joined := errors.Join(fmt.Errorf("load config: %w", openErr), errors.New("second"))
+	l.Errorw("failed", Err(fmt.Errorf("start: %w", joined)))
+	l.Errorw("no error", Err(nil))
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+	require.NotContains(t, entries[1], "error")
+
+	logged := entries[0]["error"].(map[string]interface{})
+	require.Equal(t, "start: load config: open /does/not/exist: no such file or directory\nsecond", logged["message"])
+	require.Equal(t, "*fmt.wrapError", logged["type"])
+	require.NotContains(t, logged, "stack")
+
+	chain := logged["chain"].([]interface{})
+	require.Len(t, chain, 2)
+	require.Equal(t, "*errors.joinError", chain[1].(map[string]interface{})["type"])
+
+	branches := chain[1].(map[string]interface{})["errors"].([]interface{})
+	require.Len(t, branches, 2)
+	var types []interface{}
+	for _, node := range branches[0].([]interface{}) {
+		types = append(types, node.(map[string]interface{})["type"])
+	}
+	require.Equal(t, []interface{}{"*fmt.wrapError", fmt.Sprintf("%T", &fs.PathError{}), "syscall.Errno"}, types)
+	require.Equal(t, "second", branches[1].([]interface{})[0].(map[string]interface{})["message"])
+}
+
+func TestErrStack(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)}})
+	require.Nil(t, err)
+
+	stacked := WithStack(errors.New("boom"))
+	require.Equal(t, "boom", stacked.Error())
+	require.Same(t, stacked, WithStack(stacked))
+	l.Errorw("failed", Err(fmt.Errorf("wrapped: %w", stacked)))
+	l.Errorw("traced", Err(errors.Join(errors.New("plain"), tracedError{})))
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+	require.Regexp(t, `^github\.com/justmumu/goutils/logutil\.TestErrStack\n\t\S+/logutil/errors_test\.go:\d+`,
+		entries[0]["error"].(map[string]interface{})["stack"])
+	require.Equal(t, "[pkg.fn pkg.caller]", entries[1]["error"].(map[string]interface{})["stack"])
+}
+
+func TestErrNilAndPanics(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)}})
+	require.Nil(t, err)
+
+	require.NotPanics(t, func() {
+		l.Errorw("nil", Err(fmt.Errorf("wrap: %w", error((*pointerError)(nil)))))
+		l.Errorw("typed nil", Err((*pointerError)(nil)))
+		l.Errorw("panic", Err(fmt.Errorf("wrap: %w", panicError{})))
+	})
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 3)
+	logged := entries[0]["error"].(map[string]interface{})
+	require.Equal(t, "wrap: <nil>", logged["message"])
+	require.NotContains(t, logged, "stack")
+	chain := logged["chain"].([]interface{})
+	require.Len(t, chain, 2)
+	require.Equal(t, "<nil>", chain[1].(map[string]interface{})["message"])
+	require.Equal(t, "*logutil.pointerError", chain[1].(map[string]interface{})["type"])
+
+	logged = entries[1]["error"].(map[string]interface{})
+	require.Equal(t, "<nil>", logged["message"])
+	require.Len(t, logged["chain"], 1)
+
+	// Other panics are reported like zap.Error reports them
+	require.Contains(t, entries[2]["errorError"], "PANIC=no message")
+}
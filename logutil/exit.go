@@ -0,0 +1,218 @@
+package logutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultExitTimeout = 5 * time.Second
+
+var (
+	hooksMu     sync.Mutex
+	exitHooks   []*exitHook
+	panicHooks  []*panicHook
+	exitTimeout = defaultExitTimeout
+	exitFunc    = os.Exit
+
+	// exitDone is closed once the exit hooks run by a Fatal entry are done, nil when none run
+	exitMu   sync.Mutex
+	exitDone chan struct{}
+	// callExitHooksName is the function of the goroutines running the exit hooks
+	callExitHooksName = runtime.FuncForPC(reflect.ValueOf(callExitHooks).Pointer()).Name()
+)
+
+type exitHook struct {
+	fn func(ctx context.Context)
+}
+
+type panicHook struct {
+	fn func(msg string)
+}
+
+// RegisterExitHook registers hook to run before the process exits after a Fatal entry, e.g. to
+// close connections or flush buffers of other components. Hooks run in the reverse order of their
+// registration and share the timeout set by SetExitTimeout, which also cancels ctx. The returned
+// func unregisters the hook.
+func RegisterExitHook(hook func(ctx context.Context)) (unregister func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	h := &exitHook{fn: hook}
+	exitHooks = append(exitHooks, h)
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		exitHooks = removeHook(exitHooks, h)
+	}
+}
+
+// RegisterPanicHook registers hook to run with the message of Panic entries once they are written,
+// before the logger panics. The returned func unregisters the hook.
+func RegisterPanicHook(hook func(msg string)) (unregister func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	h := &panicHook{fn: hook}
+	panicHooks = append(panicHooks, h)
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		panicHooks = removeHook(panicHooks, h)
+	}
+}
+
+func removeHook[T comparable](hooks []T, hook T) []T {
+	for i, h := range hooks {
+		if h == hook {
+			return append(hooks[:i:i], hooks[i+1:]...)
+		}
+	}
+	return hooks
+}
+
+// SetExitTimeout sets the time the exit hooks may take altogether, defaults to five seconds.
+// The process exits when the timeout expires even if hooks are still running.
+func SetExitTimeout(timeout time.Duration) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	exitTimeout = timeout
+}
+
+// SetExitFunc replaces os.Exit as the func terminating the process after Fatal entries, e.g. to
+// test code logging Fatal entries. When exit returns, the calling goroutine is stopped with
+// runtime.Goexit so the code following the Fatal call never runs. The returned func restores the
+// previous exit func.
+func SetExitFunc(exit func(code int)) (restore func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	previous := exitFunc
+	exitFunc = exit
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		exitFunc = previous
+	}
+}
+
+// fatalAction syncs the outputs of a logger and runs the exit hooks before exiting. The outputs are
+// synced again once the hooks are done, flushing the entries they logged. Fatal entries logged
+// while the hooks run wait for them, except the ones logged by the hooks which exit right away.
+type fatalAction struct {
+	root *rootCore
+}
+
+func (h fatalAction) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	_ = h.root.sync()
+
+	hooksMu.Lock()
+	hooks := append([]*exitHook(nil), exitHooks...)
+	timeout := exitTimeout
+	exit := exitFunc
+	hooksMu.Unlock()
+
+	if !inExitHook() {
+		exitMu.Lock()
+		done := exitDone
+		if done == nil {
+			exitDone = make(chan struct{})
+		}
+		exitMu.Unlock()
+
+		if done == nil {
+			runExitHooks(hooks, timeout)
+			exitMu.Lock()
+			close(exitDone)
+			exitDone = nil
+			exitMu.Unlock()
+		} else {
+			<-done
+		}
+		_ = h.root.sync()
+	}
+
+	exit(1)
+	runtime.Goexit()
+}
+
+func runExitHooks(hooks []*exitHook, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go callExitHooks(ctx, hooks, done)
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		fmt.Fprintf(os.Stderr, "%v exit hooks timed out after %s\n", time.Now(), timeout)
+	}
+}
+
+// callExitHooks runs the hooks in the reverse order of their registration, then closes done.
+func callExitHooks(ctx context.Context, hooks []*exitHook, done chan struct{}) {
+	defer close(done)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		runHook(func() { hooks[i].fn(ctx) })
+	}
+}
+
+// inExitHook reports whether the calling goroutine runs the exit hooks.
+func inExitHook() bool {
+	pcs := make([]uintptr, 64)
+	for {
+		n := runtime.Callers(2, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function == callExitHooksName {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+// runHook runs fn, reporting its panic so the remaining hooks still run.
+func runHook(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "%v log hook panicked: %v\n", time.Now(), r)
+		}
+	}()
+	fn()
+}
+
+// panicAction syncs the outputs of a logger and runs the panic hooks before panicking.
+type panicAction struct {
+	root *rootCore
+}
+
+func (h panicAction) OnWrite(ce *zapcore.CheckedEntry, _ []zapcore.Field) {
+	_ = h.root.sync()
+
+	hooksMu.Lock()
+	hooks := append([]*panicHook(nil), panicHooks...)
+	hooksMu.Unlock()
+
+	msg := h.root.redact(ce.Message)
+	for _, hook := range hooks {
+		runHook(func() { hook.fn(msg) })
+	}
+	panic(msg)
+}
@@ -0,0 +1,122 @@
+package logutil
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFatalExitHooks(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)},
+		Async: &AsyncConfig{FlushInterval: time.Hour},
+	})
+	require.Nil(t, err)
+
+	var calls []string
+	unregister := RegisterExitHook(func(context.Context) { calls = append(calls, "first") })
+	defer unregister()
+	defer RegisterExitHook(func(context.Context) { calls = append(calls, "second") })()
+	RegisterExitHook(func(context.Context) { calls = append(calls, "removed") })()
+	defer RegisterExitHook(func(context.Context) { panic("hook failure") })()
+	defer RegisterExitHook(func(context.Context) { l.Info("exiting") })()
+
+	exitCode := -1
+	var written string
+	defer SetExitFunc(func(code int) {
+		exitCode = code
+		written = buf.String()
+	})()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Fatalw("fatal", "k", "v")
+		calls = append(calls, "after fatal")
+	}()
+	<-done
+
+	require.Equal(t, 1, exitCode)
+	require.Equal(t, []string{"second", "first"}, calls)
+	// The buffered entries, including the ones of the hooks, are written before the process exits
+	entries := decodeLines(t, bytes.NewBufferString(written))
+	require.Len(t, entries, 2)
+	require.Equal(t, "fatal", entries[0]["msg"])
+	require.Equal(t, "exiting", entries[1]["msg"])
+}
+
+func TestExitTimeout(t *testing.T) {
+	l, err := NewLoggerE(LoggerConfig{})
+	require.Nil(t, err)
+
+	SetExitTimeout(10 * time.Millisecond)
+	defer SetExitTimeout(defaultExitTimeout)
+	defer RegisterExitHook(func(ctx context.Context) { <-ctx.Done() })()
+
+	exited := make(chan int, 1)
+	defer SetExitFunc(func(code int) { exited <- code })()
+
+	go l.Fatal("fatal")
+	select {
+	case code := <-exited:
+		require.Equal(t, 1, code)
+	case <-time.After(time.Second):
+		t.Fatal("exit hooks did not time out")
+	}
+}
+
+func TestConcurrentFatal(t *testing.T) {
+	l, err := NewLoggerE(LoggerConfig{})
+	require.Nil(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var nested bool
+	defer RegisterExitHook(func(context.Context) {
+		close(started)
+		<-release
+		// Fatal entries of the hooks exit right away instead of waiting for the hooks
+		l.Fatal("nested")
+		nested = true
+	})()
+
+	exited := make(chan int, 3)
+	defer SetExitFunc(func(code int) { exited <- code })()
+
+	go l.Fatal("first")
+	<-started
+
+	// A Fatal entry of another goroutine waits for the running hooks
+	go l.Fatal("second")
+	select {
+	case <-exited:
+		t.Fatal("exited before the exit hooks were done")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-exited:
+		case <-time.After(time.Second):
+			t.Fatal("fatal entries did not exit")
+		}
+	}
+	require.False(t, nested)
+}
+
+func TestPanicHooks(t *testing.T) {
+	l, err := NewLoggerE(LoggerConfig{})
+	require.Nil(t, err)
+
+	var messages []string
+	defer RegisterPanicHook(func(msg string) { messages = append(messages, msg) })()
+
+	require.PanicsWithValue(t, "boom", func() {
+		l.Panicw("boom")
+	})
+	require.Equal(t, []string{"boom"}, messages)
+}
@@ -0,0 +1,67 @@
+package logutil
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Field is a strongly typed key-value pair attached to log entries. Fields can be passed to
+// WithFields or mixed with loosely typed pairs in the w-suffixed logging methods.
+type Field = zap.Field
+
+// String constructs a field with the given key and string value.
+func String(key string, val string) Field {
+	return zap.String(key, val)
+}
+
+// Strings constructs a field with the given key and string slice value.
+func Strings(key string, val []string) Field {
+	return zap.Strings(key, val)
+}
+
+// Int constructs a field with the given key and int value.
+func Int(key string, val int) Field {
+	return zap.Int(key, val)
+}
+
+// Int64 constructs a field with the given key and int64 value.
+func Int64(key string, val int64) Field {
+	return zap.Int64(key, val)
+}
+
+// Uint64 constructs a field with the given key and uint64 value.
+func Uint64(key string, val uint64) Field {
+	return zap.Uint64(key, val)
+}
+
+// Float64 constructs a field with the given key and float64 value.
+func Float64(key string, val float64) Field {
+	return zap.Float64(key, val)
+}
+
+// Bool constructs a field with the given key and bool value.
+func Bool(key string, val bool) Field {
+	return zap.Bool(key, val)
+}
+
+// Duration constructs a field with the given key and time.Duration value.
+func Duration(key string, val time.Duration) Field {
+	return zap.Duration(key, val)
+}
+
+// Time constructs a field with the given key and time.Time value.
+func Time(key string, val time.Time) Field {
+	return zap.Time(key, val)
+}
+
+// NamedError constructs a field that lazily stores err.Error() under the given key.
+func NamedError(key string, err error) Field {
+	return zap.NamedError(key, err)
+}
+
+// Any constructs a field with the given key and an arbitrary value, choosing the best
+// way to represent it.
+func Any(key string, val interface{}) Field {
+	return zap.Any(key, val)
+}
@@ -0,0 +1,120 @@
+package logutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// levelsPayload is the JSON document served and accepted by LevelHandler.
+//
+// Example:
+//
+//	{
+//		"console": "INFO",
+//		"file": "WARN",
+//		"named": {
+//			"app.db.pool": "DEBUG"
+//		}
+//	}
+type levelsPayload struct {
+	Console *string           `json:"console,omitempty"`
+	File    *string           `json:"file,omitempty"`
+	Named   map[string]string `json:"named,omitempty"`
+}
+
+type levelHandler struct {
+	logger Logger
+}
+
+// LevelHandler returns an http.Handler reporting the levels of the logger as JSON on GET and
+// changing them on PUT or POST. Levels are case-insensitive level names. Fields missing from
+// the request body are left untouched, an empty level inside "named" removes the override.
+func LevelHandler(l Logger) http.Handler {
+	return &levelHandler{logger: l}
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var payload levelsPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			h.writeError(w, http.StatusBadRequest, fmt.Errorf("could not decode request body. err: %v", err))
+			return
+		}
+		if err := h.apply(payload); err != nil {
+			h.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		h.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	payload := levelsPayload{Named: make(map[string]string)}
+	if levels, ok := h.logger.(LevelReporter); ok {
+		console, file := levels.ConsoleLevel().String(), levels.FileLevel().String()
+		payload.Console, payload.File = &console, &file
+	}
+	if named, ok := h.logger.(NamedLeveler); ok {
+		for name, level := range named.NamedLevels() {
+			payload.Named[name] = level.String()
+		}
+	}
+	h.writeJSON(w, http.StatusOK, payload)
+}
+
+// apply parses every level of the payload before changing any of them.
+func (h *levelHandler) apply(payload levelsPayload) error {
+	var setters []func()
+
+	if payload.Console != nil {
+		level, err := ParseLogLevel(*payload.Console)
+		if err != nil {
+			return err
+		}
+		setters = append(setters, func() { h.logger.SetConsoleLevel(level) })
+	}
+
+	if payload.File != nil {
+		level, err := ParseLogLevel(*payload.File)
+		if err != nil {
+			return err
+		}
+		setters = append(setters, func() { h.logger.SetFileLevel(level) })
+	}
+
+	named, ok := h.logger.(NamedLeveler)
+	if len(payload.Named) > 0 && !ok {
+		return fmt.Errorf("logger does not support named levels")
+	}
+	for name, text := range payload.Named {
+		name := name
+		if text == "" {
+			setters = append(setters, func() { named.UnsetNamedLevel(name) })
+			continue
+		}
+		level, err := ParseLogLevel(text)
+		if err != nil {
+			return fmt.Errorf("invalid level for %q: %v", name, err)
+		}
+		setters = append(setters, func() { named.SetNamedLevel(name, level) })
+	}
+
+	for _, set := range setters {
+		set()
+	}
+	return nil
+}
+
+func (h *levelHandler) writeError(w http.ResponseWriter, status int, err error) {
+	h.writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (h *levelHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,79 @@
+package logutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelHandler(t *testing.T) {
+	l := NewLogger(LoggerConfig{ConsoleLevel: InfoLevel, FileLevel: WarnLevel})
+	server := httptest.NewServer(LevelHandler(l))
+	defer server.Close()
+
+	decode := func(resp *http.Response) levelsPayload {
+		defer resp.Body.Close()
+		var payload levelsPayload
+		require.Nil(t, json.NewDecoder(resp.Body).Decode(&payload))
+		return payload
+	}
+
+	resp, err := http.Get(server.URL)
+	require.Nil(t, err)
+	payload := decode(resp)
+	require.Equal(t, "INFO", *payload.Console)
+	require.Equal(t, "WARN", *payload.File)
+
+	body := `{"console": "debug", "named": {"app.db": "error"}}`
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(body))
+	resp, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	payload = decode(resp)
+	require.Equal(t, "DEBUG", *payload.Console)
+	require.Equal(t, "WARN", *payload.File)
+	require.Equal(t, map[string]string{"app.db": "ERROR"}, payload.Named)
+	require.Equal(t, DebugLevel, l.(LevelReporter).ConsoleLevel())
+
+	// Invalid levels are rejected without applying anything
+	body = `{"console": "info", "file": "loud"}`
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader(body))
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Equal(t, DebugLevel, l.(LevelReporter).ConsoleLevel())
+
+	body = `{"named": {"app.db": ""}}`
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader(body))
+	require.Nil(t, err)
+	require.Empty(t, decode(resp).Named)
+
+	req, _ = http.NewRequest(http.MethodDelete, server.URL, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestLevelHandlerOtherLogger(t *testing.T) {
+	// Loggers of other packages may not report their levels or support named levels
+	server := httptest.NewServer(LevelHandler(mapLogger{Logger: NewLogger(LoggerConfig{})}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	var payload levelsPayload
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&payload))
+	require.Nil(t, payload.Console)
+	require.Nil(t, payload.File)
+
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader(`{"named": {"app.db": "error"}}`))
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,211 @@
+package logutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultJournaldSocket is the native protocol socket of systemd-journald.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldConfig configures the journald sink
+type JournaldConfig struct {
+	// Name of the sink, defaults to "journald"
+	Name string
+	// Level is the initial level of the sink
+	Level LogLevel
+	// SocketPath of the journal, defaults to DefaultJournaldSocket
+	SocketPath string
+	// Identifier is the SYSLOG_IDENTIFIER of the entries, defaults to the Name of the LoggerConfig or the executable name
+	Identifier string
+	// Fallback receives text encoded entries when the journal is not available, defaults to os.Stderr
+	Fallback io.Writer
+}
+
+// NewJournaldSink returns a Sink writing entries to the systemd journal using its native protocol.
+// Fields of the entries become uppercase journal fields and levels become the PRIORITY. When the
+// journal socket is absent, the returned sink writes to the fallback writer instead. Entries the
+// journal doesn't accept, e.g. because they are too large for a datagram, are written to the
+// fallback writer as well.
+func NewJournaldSink(config JournaldConfig) Sink {
+	if config.Name == "" {
+		config.Name = "journald"
+	}
+	if config.SocketPath == "" {
+		config.SocketPath = DefaultJournaldSocket
+	}
+	if config.Fallback == nil {
+		config.Fallback = os.Stderr
+	}
+
+	fallback := NewWriterSink(config.Name, config.Fallback, config.Level, TextEncoding)
+
+	w, err := newNetWriter("unixgram", config.SocketPath)
+	if err != nil {
+		return fallback
+	}
+
+	return Sink{
+		Name:   config.Name,
+		Level:  config.Level,
+		Writer: w,
+		closer: w,
+		newCore: func(ws zapcore.WriteSyncer, loggerConfig LoggerConfig) zapcore.Core {
+			identifier := config.Identifier
+			if identifier == "" {
+				identifier = loggerConfig.Name
+			}
+			if identifier == "" {
+				identifier = filepath.Base(os.Args[0])
+			}
+			return &journaldCore{
+				ws:         ws,
+				identifier: identifier,
+				fallback:   fallback.buildCore(loggerConfig),
+			}
+		},
+	}
+}
+
+// journaldPriority maps the entry level to the journal PRIORITY, which uses the syslog severities.
+func journaldPriority(level zapcore.Level) int {
+	return syslogSeverity(level)
+}
+
+// journaldFieldName converts key to a valid journal field name: uppercase letters, digits and
+// underscores, not starting with an underscore or a digit and at most 64 characters.
+func journaldFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	name = strings.TrimLeft(name, "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// journaldCore writes entries in the journal native protocol.
+type journaldCore struct {
+	ws         zapcore.WriteSyncer
+	identifier string
+	fields     []zapcore.Field
+	fallback   zapcore.Core
+}
+
+func (c *journaldCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field(nil), c.fields...), fields...)
+	clone.fallback = c.fallback.With(fields)
+	return &clone
+}
+
+func (c *journaldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	var buf bytes.Buffer
+	writeJournaldField(&buf, "MESSAGE", ent.Message)
+	writeJournaldField(&buf, "PRIORITY", strconv.Itoa(journaldPriority(ent.Level)))
+	writeJournaldField(&buf, "SYSLOG_IDENTIFIER", c.identifier)
+	if ent.LoggerName != "" {
+		writeJournaldField(&buf, "LOGGER", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		writeJournaldField(&buf, "CODE_FILE", ent.Caller.File)
+		writeJournaldField(&buf, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		writeJournaldField(&buf, "CODE_FUNC", ent.Caller.Function)
+	}
+	if ent.Stack != "" {
+		writeJournaldField(&buf, "STACKTRACE", ent.Stack)
+	}
+
+	// Sort the keys for a stable output
+	keys := make([]string, 0, len(enc.Fields))
+	for key := range enc.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := journaldFieldName(key)
+		if name == "" {
+			continue
+		}
+		writeJournaldField(&buf, name, journaldValue(enc.Fields[key]))
+	}
+
+	if _, err := c.ws.Write(buf.Bytes()); err != nil {
+		return c.fallback.Write(ent, fields)
+	}
+	return nil
+}
+
+func (c *journaldCore) Sync() error {
+	return c.fallback.Sync()
+}
+
+// writeJournaldField appends a field in the native protocol. Values containing newlines are
+// written in the binary form, prefixed by their little-endian 64-bit length.
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if strings.ContainsRune(value, '\n') {
+		buf.WriteByte('\n')
+		_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	} else {
+		buf.WriteByte('=')
+	}
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+func journaldValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case time.Duration:
+		return val.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64:
+		return fmt.Sprint(val)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,70 @@
+package logutil
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournaldSink(t *testing.T) {
+	dir, err := os.MkdirTemp("", "journal")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.Nil(t, err)
+	defer conn.Close()
+
+	l, err := NewLoggerE(LoggerConfig{
+		Name:  "app",
+		Sinks: []Sink{NewJournaldSink(JournaldConfig{SocketPath: socket})},
+	})
+	require.Nil(t, err)
+
+	l.With("request-id", "abc").Warnw("multi\nline", "count", 2)
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	require.Nil(t, err)
+	datagram := string(buf[:n])
+
+	require.True(t, strings.HasPrefix(datagram, "MESSAGE\n\x0a\x00\x00\x00\x00\x00\x00\x00multi\nline\n"))
+	require.Contains(t, datagram, "PRIORITY=4\n")
+	require.Contains(t, datagram, "SYSLOG_IDENTIFIER=app\n")
+	require.Contains(t, datagram, "LOGGER=app\n")
+	require.Contains(t, datagram, "COUNT=2\n")
+	require.Contains(t, datagram, "REQUEST_ID=abc\n")
+}
+
+func TestJournaldSinkFallback(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		Sinks: []Sink{NewJournaldSink(JournaldConfig{
+			SocketPath: filepath.Join(t.TempDir(), "missing"),
+			Fallback:   &buf,
+		})},
+	})
+	require.Nil(t, err)
+
+	l.Info("no journal")
+	require.Contains(t, buf.String(), "INFO\tno journal")
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	tests := map[string]string{
+		"request-id": "REQUEST_ID",
+		"_private":   "PRIVATE",
+		"2fa":        "FA",
+		"user.name":  "USER_NAME",
+		"__":         "",
+	}
+	for key, expected := range tests {
+		require.Equal(t, expected, journaldFieldName(key))
+	}
+}
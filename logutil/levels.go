@@ -1,5 +1,12 @@
 package logutil
 
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
 type LogLevel uint8
 
 const (
@@ -14,10 +21,74 @@ const (
 	ErrorLevel
 	// PanicLevel logs a message, then panics.
 	PanicLevel
-	// FatalLevel logs a message, then calls os.Exit(1).
+	// FatalLevel logs a message, runs the exit hooks, then calls os.Exit(1), see RegisterExitHook.
 	FatalLevel
 )
 
 func (l LogLevel) String() string {
 	return [...]string{"DEBUG", "INFO", "WARN", "ERROR", "PANIC", "FATAL"}[l]
 }
+
+// MarshalText marshals the LogLevel to its lowercase name.
+func (l LogLevel) MarshalText() ([]byte, error) {
+	if l > FatalLevel {
+		return nil, fmt.Errorf("unknown log level: %d", l)
+	}
+	return []byte(strings.ToLower(l.String())), nil
+}
+
+// UnmarshalText unmarshals a case-insensitive level name such as "debug" to a LogLevel.
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	level, err := ParseLogLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// zapLevel returns the zapcore.Level corresponding to the LogLevel.
+func (l LogLevel) zapLevel() zapcore.Level {
+	switch l {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case InfoLevel:
+		return zapcore.InfoLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	case ErrorLevel:
+		return zapcore.ErrorLevel
+	case PanicLevel:
+		return zapcore.PanicLevel
+	default:
+		return zapcore.FatalLevel
+	}
+}
+
+// fromZapLevel returns the LogLevel corresponding to the zapcore.Level.
+func fromZapLevel(level zapcore.Level) LogLevel {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return DebugLevel
+	case level == zapcore.InfoLevel:
+		return InfoLevel
+	case level == zapcore.WarnLevel:
+		return WarnLevel
+	case level == zapcore.ErrorLevel:
+		return ErrorLevel
+	case level <= zapcore.PanicLevel:
+		return PanicLevel
+	default:
+		return FatalLevel
+	}
+}
+
+// ParseLogLevel returns the LogLevel with the given case-insensitive name, e.g. "debug" or "WARN".
+func ParseLogLevel(text string) (LogLevel, error) {
+	for l := DebugLevel; l <= FatalLevel; l++ {
+		if strings.EqualFold(text, l.String()) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level: %q", text)
+}
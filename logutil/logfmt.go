@@ -0,0 +1,350 @@
+package logutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder encodes entries as logfmt lines, e.g.
+//
+//	ts=2024-01-02T15:04:05.000Z level=info logger=app msg="hello world" key=value
+//
+// Nested objects and namespaces are flattened into dotted keys, arrays are encoded as
+// comma-separated values in brackets.
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf        *buffer.Buffer
+	namespaces []string
+}
+
+func newLogfmtEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{
+		EncoderConfig: &config,
+		buf:           logfmtPool.Get(),
+	}
+}
+
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := enc.clone()
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *logfmtEncoder) clone() *logfmtEncoder {
+	return &logfmtEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		buf:           logfmtPool.Get(),
+		namespaces:    append([]string(nil), enc.namespaces...),
+	}
+}
+
+func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+
+	if final.TimeKey != "" && !ent.Time.IsZero() {
+		final.appendEncoded(final.TimeKey, func(arr zapcore.PrimitiveArrayEncoder) {
+			if final.EncodeTime != nil {
+				final.EncodeTime(ent.Time, arr)
+			}
+		}, ent.Time.Format(time.RFC3339Nano))
+	}
+	if final.LevelKey != "" {
+		final.appendEncoded(final.LevelKey, func(arr zapcore.PrimitiveArrayEncoder) {
+			if final.EncodeLevel != nil {
+				final.EncodeLevel(ent.Level, arr)
+			}
+		}, ent.Level.String())
+	}
+	if final.NameKey != "" && ent.LoggerName != "" {
+		final.appendEncoded(final.NameKey, func(arr zapcore.PrimitiveArrayEncoder) {
+			if final.EncodeName != nil {
+				final.EncodeName(ent.LoggerName, arr)
+			}
+		}, ent.LoggerName)
+	}
+	if final.CallerKey != "" && ent.Caller.Defined {
+		final.appendEncoded(final.CallerKey, func(arr zapcore.PrimitiveArrayEncoder) {
+			if final.EncodeCaller != nil {
+				final.EncodeCaller(ent.Caller, arr)
+			}
+		}, ent.Caller.TrimmedPath())
+		if final.FunctionKey != "" {
+			final.appendValue(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.appendValue(final.MessageKey, ent.Message)
+	}
+
+	if enc.buf.Len() > 0 {
+		final.buf.AppendByte(' ')
+		final.buf.Write(enc.buf.Bytes())
+	}
+	for _, field := range fields {
+		field.AddTo(final)
+	}
+
+	if final.StacktraceKey != "" && ent.Stack != "" {
+		final.appendValue(final.StacktraceKey, ent.Stack)
+	}
+
+	if final.LineEnding != "" {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return final.buf, nil
+}
+
+// appendEncoded appends the value produced by encode, or fallback when encode produced nothing.
+func (enc *logfmtEncoder) appendEncoded(key string, encode func(zapcore.PrimitiveArrayEncoder), fallback string) {
+	arr := &logfmtArrayEncoder{config: enc.EncoderConfig}
+	encode(arr)
+	if len(arr.elems) == 0 {
+		enc.appendValue(key, fallback)
+		return
+	}
+	enc.appendRaw(key, strings.Join(arr.elems, ","))
+}
+
+// appendValue appends a string value, quoted when needed.
+func (enc *logfmtEncoder) appendValue(key, value string) {
+	enc.appendRaw(key, logfmtQuote(value))
+}
+
+// appendRaw appends a value which doesn't need quoting.
+func (enc *logfmtEncoder) appendRaw(key, value string) {
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+	for _, ns := range enc.namespaces {
+		enc.buf.AppendString(logfmtKey(ns))
+		enc.buf.AppendByte('.')
+	}
+	enc.buf.AppendString(logfmtKey(key))
+	enc.buf.AppendByte('=')
+	enc.buf.AppendString(value)
+}
+
+func (enc *logfmtEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arr := &logfmtArrayEncoder{config: enc.EncoderConfig}
+	err := marshaler.MarshalLogArray(arr)
+	enc.appendValue(key, arr.String())
+	return err
+}
+
+func (enc *logfmtEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	// Namespaces opened by the object end with it
+	namespaces := enc.namespaces
+	enc.namespaces = append(append([]string(nil), namespaces...), key)
+	err := marshaler.MarshalLogObject(enc)
+	enc.namespaces = namespaces
+	return err
+}
+
+func (enc *logfmtEncoder) AddBinary(key string, value []byte) {
+	enc.appendRaw(key, base64.StdEncoding.EncodeToString(value))
+}
+
+func (enc *logfmtEncoder) AddByteString(key string, value []byte) {
+	enc.appendValue(key, string(value))
+}
+
+func (enc *logfmtEncoder) AddBool(key string, value bool) {
+	enc.appendRaw(key, strconv.FormatBool(value))
+}
+
+func (enc *logfmtEncoder) AddComplex128(key string, value complex128) {
+	enc.appendRaw(key, strconv.FormatComplex(value, 'g', -1, 128))
+}
+
+func (enc *logfmtEncoder) AddComplex64(key string, value complex64) {
+	enc.appendRaw(key, strconv.FormatComplex(complex128(value), 'g', -1, 64))
+}
+
+func (enc *logfmtEncoder) AddDuration(key string, value time.Duration) {
+	enc.appendEncoded(key, func(arr zapcore.PrimitiveArrayEncoder) {
+		if enc.EncodeDuration != nil {
+			enc.EncodeDuration(value, arr)
+		}
+	}, value.String())
+}
+
+func (enc *logfmtEncoder) AddFloat64(key string, value float64) {
+	enc.appendRaw(key, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (enc *logfmtEncoder) AddFloat32(key string, value float32) {
+	enc.appendRaw(key, strconv.FormatFloat(float64(value), 'g', -1, 32))
+}
+
+func (enc *logfmtEncoder) AddInt(key string, value int)     { enc.AddInt64(key, int64(value)) }
+func (enc *logfmtEncoder) AddInt32(key string, value int32) { enc.AddInt64(key, int64(value)) }
+func (enc *logfmtEncoder) AddInt16(key string, value int16) { enc.AddInt64(key, int64(value)) }
+func (enc *logfmtEncoder) AddInt8(key string, value int8)   { enc.AddInt64(key, int64(value)) }
+
+func (enc *logfmtEncoder) AddInt64(key string, value int64) {
+	enc.appendRaw(key, strconv.FormatInt(value, 10))
+}
+
+func (enc *logfmtEncoder) AddString(key, value string) {
+	enc.appendValue(key, value)
+}
+
+func (enc *logfmtEncoder) AddTime(key string, value time.Time) {
+	enc.appendEncoded(key, func(arr zapcore.PrimitiveArrayEncoder) {
+		if enc.EncodeTime != nil {
+			enc.EncodeTime(value, arr)
+		}
+	}, value.Format(time.RFC3339Nano))
+}
+
+func (enc *logfmtEncoder) AddUint(key string, value uint)       { enc.AddUint64(key, uint64(value)) }
+func (enc *logfmtEncoder) AddUint32(key string, value uint32)   { enc.AddUint64(key, uint64(value)) }
+func (enc *logfmtEncoder) AddUint16(key string, value uint16)   { enc.AddUint64(key, uint64(value)) }
+func (enc *logfmtEncoder) AddUint8(key string, value uint8)     { enc.AddUint64(key, uint64(value)) }
+func (enc *logfmtEncoder) AddUintptr(key string, value uintptr) { enc.AddUint64(key, uint64(value)) }
+
+func (enc *logfmtEncoder) AddUint64(key string, value uint64) {
+	enc.appendRaw(key, strconv.FormatUint(value, 10))
+}
+
+func (enc *logfmtEncoder) AddReflected(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	enc.appendValue(key, string(data))
+	return nil
+}
+
+func (enc *logfmtEncoder) OpenNamespace(key string) {
+	enc.namespaces = append(enc.namespaces, key)
+}
+
+// logfmtArrayEncoder collects the encoded elements of arrays and of the values produced
+// by the encoders of the EncoderConfig.
+type logfmtArrayEncoder struct {
+	config *zapcore.EncoderConfig
+	elems  []string
+}
+
+func (arr *logfmtArrayEncoder) String() string {
+	return "[" + strings.Join(arr.elems, ",") + "]"
+}
+
+func (arr *logfmtArrayEncoder) append(value string) {
+	arr.elems = append(arr.elems, value)
+}
+
+func (arr *logfmtArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	nested := &logfmtArrayEncoder{config: arr.config}
+	err := marshaler.MarshalLogArray(nested)
+	arr.append(nested.String())
+	return err
+}
+
+func (arr *logfmtArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	nested := &logfmtEncoder{EncoderConfig: arr.config, buf: logfmtPool.Get()}
+	defer nested.buf.Free()
+	err := marshaler.MarshalLogObject(nested)
+	arr.append("{" + nested.buf.String() + "}")
+	return err
+}
+
+func (arr *logfmtArrayEncoder) AppendReflected(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	arr.append(string(data))
+	return nil
+}
+
+func (arr *logfmtArrayEncoder) AppendDuration(value time.Duration) {
+	nested := &logfmtArrayEncoder{config: arr.config}
+	if arr.config.EncodeDuration != nil {
+		arr.config.EncodeDuration(value, nested)
+	}
+	if len(nested.elems) == 0 {
+		nested.append(value.String())
+	}
+	arr.elems = append(arr.elems, nested.elems...)
+}
+
+func (arr *logfmtArrayEncoder) AppendTime(value time.Time) {
+	nested := &logfmtArrayEncoder{config: arr.config}
+	if arr.config.EncodeTime != nil {
+		arr.config.EncodeTime(value, nested)
+	}
+	if len(nested.elems) == 0 {
+		nested.append(value.Format(time.RFC3339Nano))
+	}
+	arr.elems = append(arr.elems, nested.elems...)
+}
+
+func (arr *logfmtArrayEncoder) AppendBool(value bool) { arr.append(strconv.FormatBool(value)) }
+func (arr *logfmtArrayEncoder) AppendByteString(value []byte) {
+	arr.append(logfmtQuote(string(value)))
+}
+func (arr *logfmtArrayEncoder) AppendComplex128(value complex128) {
+	arr.append(strconv.FormatComplex(value, 'g', -1, 128))
+}
+func (arr *logfmtArrayEncoder) AppendComplex64(value complex64) {
+	arr.append(strconv.FormatComplex(complex128(value), 'g', -1, 64))
+}
+func (arr *logfmtArrayEncoder) AppendFloat64(value float64) {
+	arr.append(strconv.FormatFloat(value, 'g', -1, 64))
+}
+func (arr *logfmtArrayEncoder) AppendFloat32(value float32) {
+	arr.append(strconv.FormatFloat(float64(value), 'g', -1, 32))
+}
+func (arr *logfmtArrayEncoder) AppendInt(value int)         { arr.AppendInt64(int64(value)) }
+func (arr *logfmtArrayEncoder) AppendInt64(value int64)     { arr.append(strconv.FormatInt(value, 10)) }
+func (arr *logfmtArrayEncoder) AppendInt32(value int32)     { arr.AppendInt64(int64(value)) }
+func (arr *logfmtArrayEncoder) AppendInt16(value int16)     { arr.AppendInt64(int64(value)) }
+func (arr *logfmtArrayEncoder) AppendInt8(value int8)       { arr.AppendInt64(int64(value)) }
+func (arr *logfmtArrayEncoder) AppendString(value string)   { arr.append(logfmtQuote(value)) }
+func (arr *logfmtArrayEncoder) AppendUint(value uint)       { arr.AppendUint64(uint64(value)) }
+func (arr *logfmtArrayEncoder) AppendUint64(value uint64)   { arr.append(strconv.FormatUint(value, 10)) }
+func (arr *logfmtArrayEncoder) AppendUint32(value uint32)   { arr.AppendUint64(uint64(value)) }
+func (arr *logfmtArrayEncoder) AppendUint16(value uint16)   { arr.AppendUint64(uint64(value)) }
+func (arr *logfmtArrayEncoder) AppendUint8(value uint8)     { arr.AppendUint64(uint64(value)) }
+func (arr *logfmtArrayEncoder) AppendUintptr(value uintptr) { arr.AppendUint64(uint64(value)) }
+
+// logfmtKey replaces the characters which are not allowed in logfmt keys by underscores.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtQuote quotes the value when it is empty or contains spaces, quotes, equal signs or
+// unprintable characters.
+func logfmtQuote(value string) string {
+	if value == "" {
+		return `""`
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
@@ -0,0 +1,50 @@
+package logutil
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLogfmtEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		Name:  "app",
+		Sinks: []Sink{NewWriterSink("logfmt", &buf, DebugLevel, LogfmtEncoding)},
+	})
+	require.Nil(t, err)
+
+	l.With("component", "db").Infow("hello world",
+		"n", 3,
+		"quoted", `say "hi"`,
+		"empty", "",
+		"took", time.Second,
+		"tags", []string{"a", "b c"},
+		zap.Namespace("req"),
+		"id", 7,
+		"err", errors.New("boom"),
+	)
+
+	pattern := `^ts=\S+ level=info logger=app msg="hello world" component=db n=3 quoted="say \\"hi\\"" empty="" ` +
+		`took=1000000000 tags="\[a,\\"b c\\"\]" req\.id=7 req\.err=boom\n$`
+	require.Regexp(t, regexp.MustCompile(pattern), buf.String())
+}
+
+func TestLoggerConfigEncoding(t *testing.T) {
+	require.Equal(t, JSONEncoding, outputEncoding(TextEncoding, true))
+	require.Equal(t, LogfmtEncoding, outputEncoding(LogfmtEncoding, true))
+	require.Equal(t, TextEncoding, outputEncoding(TextEncoding, false))
+
+	config, err := LoggerConfigFromYAML([]byte("console_encoding: logfmt\nfile_encoding: JSON\n"))
+	require.Nil(t, err)
+	require.Equal(t, LogfmtEncoding, config.ConsoleEncoding)
+	require.Equal(t, JSONEncoding, config.FileEncoding)
+
+	_, err = LoggerConfigFromYAML([]byte("console_encoding: xml\n"))
+	require.NotNil(t, err)
+}
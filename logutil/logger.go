@@ -1,9 +1,14 @@
 package logutil
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/justmumu/goutils/fileutil"
 	"go.uber.org/zap"
@@ -12,28 +17,79 @@ import (
 )
 
 // Configuration for logging
+//
+// LoggerConfig can be unmarshalled from JSON and YAML, levels are given by name. See LoggerConfigFromEnv
+// for the environment variables.
 type LoggerConfig struct {
 	// Name is the name of the logger
-	Name string
-
-	ConsoleEnabled bool
-	ConsoleLevel   LogLevel
-	ConsoleJson    bool
-
-	FileEnabled bool
-	FileLevel   LogLevel
-	FileJson    bool
+	Name string `json:"name" yaml:"name" env:"NAME"`
+
+	ConsoleEnabled  bool     `json:"console_enabled" yaml:"console_enabled" env:"CONSOLE_ENABLED"`
+	ConsoleLevel    LogLevel `json:"console_level" yaml:"console_level" env:"CONSOLE_LEVEL"`
+	ConsoleEncoding Encoding `json:"console_encoding" yaml:"console_encoding" env:"CONSOLE_ENCODING"`
+	// Deprecated: ConsoleJson selects the JSONEncoding when ConsoleEncoding is left as TextEncoding, use ConsoleEncoding instead.
+	ConsoleJson bool `json:"console_json" yaml:"console_json" env:"CONSOLE_JSON"`
+	// ConsoleTimeFormat, ConsoleTimeZone and ConsoleDurationFormat configure the console output like
+	// the TimeFormat, TimeZone and DurationFormat of a Sink.
+	ConsoleTimeFormat     string `json:"console_time_format" yaml:"console_time_format" env:"CONSOLE_TIME_FORMAT"`
+	ConsoleTimeZone       string `json:"console_time_zone" yaml:"console_time_zone" env:"CONSOLE_TIME_ZONE"`
+	ConsoleDurationFormat string `json:"console_duration_format" yaml:"console_duration_format" env:"CONSOLE_DURATION_FORMAT"`
+
+	FileEnabled  bool     `json:"file_enabled" yaml:"file_enabled" env:"FILE_ENABLED"`
+	FileLevel    LogLevel `json:"file_level" yaml:"file_level" env:"FILE_LEVEL"`
+	FileEncoding Encoding `json:"file_encoding" yaml:"file_encoding" env:"FILE_ENCODING"`
+	// Deprecated: FileJson selects the JSONEncoding when FileEncoding is left as TextEncoding, use FileEncoding instead.
+	FileJson bool `json:"file_json" yaml:"file_json" env:"FILE_JSON"`
+	// FileTimeFormat, FileTimeZone and FileDurationFormat configure the file output like the
+	// TimeFormat, TimeZone and DurationFormat of a Sink.
+	FileTimeFormat     string `json:"file_time_format" yaml:"file_time_format" env:"FILE_TIME_FORMAT"`
+	FileTimeZone       string `json:"file_time_zone" yaml:"file_time_zone" env:"FILE_TIME_ZONE"`
+	FileDurationFormat string `json:"file_duration_format" yaml:"file_duration_format" env:"FILE_DURATION_FORMAT"`
 
 	// LogDirectory to log to when file logging is enabled
-	LogDirectory string
+	LogDirectory string `json:"log_directory" yaml:"log_directory" env:"LOG_DIRECTORY"`
 	// Filename is the name of the log file which will be placed inside the directory
-	Filename string
+	Filename string `json:"filename" yaml:"filename" env:"FILENAME"`
 	// MaxSize the max size in MB of the logfile before it is rotated
-	MaxSize int
+	MaxSize int `json:"max_size" yaml:"max_size" env:"MAX_SIZE"`
 	// MaxBackups the max number of rotated files to keep
-	MaxBackup int
+	MaxBackup int `json:"max_backups" yaml:"max_backups" env:"MAX_BACKUPS"`
 	// MaxAge the max age in days to keep a logfile
-	MaxAge int
+	MaxAge int `json:"max_age" yaml:"max_age" env:"MAX_AGE"`
+	// Rotation rotates the logfile at the start of every hour or day, in addition to MaxSize
+	Rotation RotationInterval `json:"rotation" yaml:"rotation" env:"ROTATION"`
+	// Compress gzips the rotated files
+	Compress bool `json:"compress" yaml:"compress" env:"COMPRESS"`
+	// LocalTime uses the local time in the names of the rotated files and for the rotation
+	// boundaries, UTC otherwise
+	LocalTime bool `json:"local_time" yaml:"local_time" env:"LOCAL_TIME"`
+	// MaxTotalSize the max size in MB of the files in LogDirectory, the oldest rotated files of the
+	// logfile are removed beyond it. Files of other loggers count but are not removed.
+	MaxTotalSize int `json:"max_total_size" yaml:"max_total_size" env:"MAX_TOTAL_SIZE"`
+
+	// Sinks are additional outputs next to the console and the file
+	Sinks []Sink `json:"-" yaml:"-"`
+
+	// Sampling limits the entries with the same level and message logged per interval
+	Sampling *SamplingConfig `json:"sampling,omitempty" yaml:"sampling,omitempty" env:"SAMPLING"`
+	// RateLimit limits the rate of the entries with the same logger name, level and message
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty" env:"RATE_LIMIT"`
+	// DropReportInterval is the interval of the entries reporting the number of entries dropped by
+	// sampling, rate limiting and async queue overflows, defaults to 10 seconds. Drops are also
+	// reported on Sync.
+	DropReportInterval time.Duration `json:"drop_report_interval" yaml:"drop_report_interval" env:"DROP_REPORT_INTERVAL"`
+
+	// Async enables asynchronous writing through a bounded queue per sink
+	Async *AsyncConfig `json:"async,omitempty" yaml:"async,omitempty" env:"ASYNC"`
+
+	// Redaction masks sensitive values in the messages and fields of the entries
+	Redaction *RedactionConfig `json:"redaction,omitempty" yaml:"redaction,omitempty" env:"REDACTION"`
+
+	// CallerEnabled annotates entries with the file and line of the logging call
+	CallerEnabled bool `json:"caller_enabled" yaml:"caller_enabled" env:"CALLER_ENABLED"`
+	// StacktraceEnabled records a stack trace for the entries at or above StacktraceLevel
+	StacktraceEnabled bool     `json:"stacktrace_enabled" yaml:"stacktrace_enabled" env:"STACKTRACE_ENABLED"`
+	StacktraceLevel   LogLevel `json:"stacktrace_level" yaml:"stacktrace_level" env:"STACKTRACE_LEVEL"`
 }
 
 type Logger interface {
@@ -109,6 +165,28 @@ type Logger interface {
 	// variadic key-value pairs are treated as they are in With.
 	Fatalw(msg string, keysAndValues ...interface{})
 
+	// With returns a child logger carrying the given key-value pairs on every entry. The variadic
+	// key-value pairs are treated as they are in Infow. The child shares the levels of its parent.
+	With(keysAndValues ...interface{}) Logger
+	// WithFields returns a child logger carrying the given strongly typed fields on every entry.
+	// The child shares the levels of its parent.
+	WithFields(fields ...Field) Logger
+	// WithContext returns a child logger carrying the fields extracted from ctx by
+	// ContextWithFields and the registered context extractors.
+	WithContext(ctx context.Context) Logger
+	// DebugCtx logs a message at [DebugLevel] with the fields extracted from ctx and some additional context.
+	DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{})
+	// InfoCtx logs a message at [InfoLevel] with the fields extracted from ctx and some additional context.
+	InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{})
+	// WarnCtx logs a message at [WarnLevel] with the fields extracted from ctx and some additional context.
+	WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{})
+	// ErrorCtx logs a message at [ErrorLevel] with the fields extracted from ctx and some additional context.
+	ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{})
+	// PanicCtx logs a message with the fields extracted from ctx and some additional context, then panics.
+	PanicCtx(ctx context.Context, msg string, keysAndValues ...interface{})
+	// FatalCtx logs a message with the fields extracted from ctx and some additional context, then calls os.Exit.
+	FatalCtx(ctx context.Context, msg string, keysAndValues ...interface{})
+
 	// Named adds a new path segment to the logger's name. Segments are joined by
 	// periods. By default, Loggers are unnamed.
 	Named(s string) Logger
@@ -121,117 +199,405 @@ type Logger interface {
 	SetFileLevel(level LogLevel)
 }
 
+// Rotator rotates the log file. The loggers built by the package implement it, a Logger is
+// type-asserted to it.
+type Rotator interface {
+	// Rotate closes the log file, renames it with a timestamp and opens a new one. It does nothing
+	// when the file output is disabled.
+	Rotate() error
+}
+
+// ConfigApplier applies configs to a running logger. The loggers built by the package implement it,
+// a Logger is type-asserted to it.
+type ConfigApplier interface {
+	// ApplyConfig applies config to the logger and all loggers derived from it, see WatchConfig.
+	ApplyConfig(config LoggerConfig) error
+}
+
+// LevelReporter reports the levels set by SetConsoleLevel and SetFileLevel. The loggers built by
+// the package implement it, a Logger is type-asserted to it.
+type LevelReporter interface {
+	// ConsoleLevel returns the logging level of the console logger.
+	ConsoleLevel() LogLevel
+	// FileLevel returns the logging level of the file logger.
+	FileLevel() LogLevel
+}
+
+// SinkLeveler changes the levels of the sinks. The loggers built by the package implement it,
+// a Logger is type-asserted to it.
+type SinkLeveler interface {
+	// SetSinkLevel sets the logging level of the sink with the given name. The console and file
+	// loggers are the sinks named ConsoleSinkName and FileSinkName.
+	SetSinkLevel(name string, level LogLevel) error
+	// SinkLevel returns the logging level of the sink with the given name.
+	SinkLevel(name string) (LogLevel, bool)
+}
+
+// NamedLeveler overrides the levels of named loggers. The loggers built by the package implement it,
+// a Logger is type-asserted to it.
+type NamedLeveler interface {
+	// SetNamedLevel overrides the sink levels for the logger with the given name and all of
+	// its descendants, e.g. "app.db" also applies to "app.db.pool". Names are the dotted names produced
+	// by Named, including the name from the LoggerConfig. The longest matching name wins.
+	SetNamedLevel(name string, level LogLevel)
+	// UnsetNamedLevel removes the level override registered for the given name.
+	UnsetNamedLevel(name string)
+	// NamedLevel returns the level override in effect for the logger with the given name.
+	NamedLevel(name string) (LogLevel, bool)
+	// NamedLevels returns all registered level overrides keyed by logger name.
+	NamedLevels() map[string]LogLevel
+}
+
 type logger struct {
 	consoleAtomLvl zap.AtomicLevel
 	fileAtomLvl    zap.AtomicLevel
+	overrides      *levelOverrides
+	sinkLvls       map[string]zap.AtomicLevel
+	callerEnabled  bool
+	// root holds the core shared by the logger and its children, swapped by ApplyConfig
+	root *rootCore
 
 	unsugared *zap.Logger
 	*zap.SugaredLogger
+	// skipped skips the frame of the logger's own methods wrapping the SugaredLogger
+	skipped *zap.SugaredLogger
 }
 
+// NewLogger returns a Logger configured by config. Invalid values are tolerated for compatibility:
+// the outputs which can't be set up, such as a file output whose directory can't be created or a
+// sink with a duplicate name, are dropped and the problems are logged to the DefaultLogger. Use
+// NewLoggerE to get an error instead. The returned Logger is an io.Closer, see Close.
 func NewLogger(config LoggerConfig) Logger {
+	ll, err := newLogger(config)
+	if err != nil && DefaultLogger != nil {
+		Errorf("could not create logger. err: %v", err)
+	}
+	return ll
+}
+
+// NewLoggerE returns a Logger configured by config, or an error when the config is invalid
+// or the log directory is not writable. The returned Logger is an io.Closer, see Close.
+func NewLoggerE(config LoggerConfig) (Logger, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	ll, err := newLogger(config)
+	if err != nil {
+		return nil, err
+	}
+	return ll, nil
+}
+
+// newLogger builds the logger. The returned logger is usable even when an error is returned,
+// it lacks the outputs which couldn't be set up.
+func newLogger(config LoggerConfig) (*logger, error) {
+	var buildErr error
 	ll := &logger{}
 	// Prepare logging level
-	var consoleLevel zapcore.Level
-	consoleLevel.Set(strings.ToLower(config.ConsoleLevel.String()))
-	ll.consoleAtomLvl = zap.NewAtomicLevelAt(consoleLevel)
-
-	var fileLevel zapcore.Level
-	fileLevel.Set(strings.ToLower(config.FileLevel.String()))
-	ll.fileAtomLvl = zap.NewAtomicLevelAt(fileLevel)
-
-	// Prepare encoder configs
-	consoleEncoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "ts",
-		LevelKey:       "level",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		MessageKey:     "msg",
-		StacktraceKey:  "stacktrace",
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.NanosDurationEncoder,
-	}
-
-	jsonEncoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "ts",
-		LevelKey:       "level",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		MessageKey:     "msg",
-		StacktraceKey:  "stacktrace",
-		EncodeLevel:    zapcore.LowercaseLevelEncoder,
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.NanosDurationEncoder,
-	}
-
-	// Prepare encoders
-	jsonEncoder := zapcore.NewJSONEncoder(jsonEncoderConfig)
-
-	// Prepare zap cores
-	var cores []zapcore.Core
+	ll.consoleAtomLvl = zap.NewAtomicLevelAt(config.ConsoleLevel.zapLevel())
+	ll.fileAtomLvl = zap.NewAtomicLevelAt(config.FileLevel.zapLevel())
+	ll.overrides = newLevelOverrides()
+	ll.sinkLvls = map[string]zap.AtomicLevel{
+		ConsoleSinkName: ll.consoleAtomLvl,
+		FileSinkName:    ll.fileAtomLvl,
+	}
+
+	// Custom sinks are kept for the lifetime of the logger
+	var sinks []Sink
+	for _, sink := range config.Sinks {
+		if _, ok := ll.sinkLvls[sink.Name]; ok {
+			buildErr = errors.Join(buildErr, fmt.Errorf("duplicate sink name: %q", sink.Name))
+			continue
+		}
+		ll.sinkLvls[sink.Name] = zap.NewAtomicLevelAt(sink.Level.zapLevel())
+		sinks = append(sinks, sink)
+	}
+	config.Sinks = sinks
+
+	built, err := ll.build(config, nil)
+	buildErr = errors.Join(buildErr, err)
+	ll.root = &rootCore{config: config}
+	ll.root.current.Store(built)
+
+	// Prepare zap logger instance
+	opts := []zap.Option{
+		zap.WithFatalHook(fatalAction{root: ll.root}),
+		zap.WithPanicHook(panicAction{root: ll.root}),
+	}
+	if config.CallerEnabled {
+		opts = append(opts, zap.AddCaller())
+		ll.callerEnabled = true
+	}
+	if config.StacktraceEnabled {
+		opts = append(opts, zap.AddStacktrace(config.StacktraceLevel.zapLevel()))
+	}
+	unsugared := zap.New(&swapCore{root: ll.root}, opts...)
+
+	if name := rootName(config); name != "" {
+		unsugared = unsugared.Named(name)
+	}
+	ll.setUnsugared(unsugared)
+
+	return ll, buildErr
+}
+
+// build builds the core writing to the outputs of config, reusing file when it isn't nil. The
+// returned core is usable even when an error is returned, it lacks the outputs which couldn't be set up.
+func (l *logger) build(config LoggerConfig, file *rotateFile) (*builtCore, error) {
+	var buildErr error
+	built := &builtCore{}
+
+	// Prepare sinks, the console and file outputs come first
+	var sinks []Sink
 
 	if config.ConsoleEnabled {
-		if config.ConsoleJson {
-			cores = append(cores, zapcore.NewCore(jsonEncoder, zapcore.Lock(os.Stderr), ll.consoleAtomLvl))
+		sinks = append(sinks, Sink{
+			Name:           ConsoleSinkName,
+			Encoding:       outputEncoding(config.ConsoleEncoding, config.ConsoleJson),
+			Color:          true,
+			Writer:         os.Stderr,
+			TimeFormat:     config.ConsoleTimeFormat,
+			TimeZone:       config.ConsoleTimeZone,
+			DurationFormat: config.ConsoleDurationFormat,
+		})
+	}
+
+	if config.FileEnabled {
+		var err error
+		if file == nil {
+			file, err = newRotateFile(config)
+		}
+		if err != nil {
+			buildErr = err
 		} else {
-			coloredTextEncoderConfig := consoleEncoderConfig
-			coloredTextEncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-			coloredTextEncoder := zapcore.NewConsoleEncoder(coloredTextEncoderConfig)
-			cores = append(cores, zapcore.NewCore(coloredTextEncoder, zapcore.Lock(os.Stderr), ll.consoleAtomLvl))
+			// The file is closed after the closers, which may write to it
+			built.file = file
+			sinks = append(sinks, Sink{
+				Name:           FileSinkName,
+				Encoding:       outputEncoding(config.FileEncoding, config.FileJson),
+				Writer:         file,
+				TimeFormat:     config.FileTimeFormat,
+				TimeZone:       config.FileTimeZone,
+				DurationFormat: config.FileDurationFormat,
+			})
 		}
 	}
 
-	if config.FileEnabled {
-		fileSyncer := newRotateFile(config)
-		if fileSyncer != nil {
-			if config.FileJson {
-				cores = append(cores, zapcore.NewCore(jsonEncoder, newRotateFile(config), ll.fileAtomLvl))
-			} else {
-				uncoloredTextEncoderConfig := consoleEncoderConfig
-				uncoloredTextEncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
-				uncoloredTextEncoder := zapcore.NewConsoleEncoder(uncoloredTextEncoderConfig)
-				cores = append(cores, zapcore.NewCore(uncoloredTextEncoder, newRotateFile(config), ll.fileAtomLvl))
-			}
+	sinks = append(sinks, config.Sinks...)
+
+	// Prepare the counter of dropped entries
+	var counter *dropCounter
+	var onDrop func()
+	if config.Sampling != nil || config.RateLimit != nil || config.Async != nil {
+		counter = newDropCounter(config.DropReportInterval, rootName(config))
+		onDrop = counter.add
+	}
+
+	// Prepare the redaction of sensitive values
+	var redactor *redactor
+	if config.Redaction != nil {
+		var err error
+		redactor, err = newRedactor(*config.Redaction)
+		buildErr = errors.Join(buildErr, err)
+	}
+
+	// Prepare zap cores. Cores accept every level, overrideCore gates them by the sink level
+	// or the named level overrides.
+	var cores []zapcore.Core
+	for _, sink := range sinks {
+		if config.Async != nil && sink.Writer != nil {
+			// Sinks with their own core may rely on one write per entry
+			w := newAsyncWriter(zapcore.Lock(writeSyncer(sink.Writer)), *config.Async, sink.newCore == nil, onDrop)
+			built.closers = append(built.closers, w)
+			sink.Writer = w
 		}
+
+		core := sink.buildCore(config)
+		cores = append(cores, newOverrideCore(core, l.sinkLvls[sink.Name], l.overrides))
 	}
 	core := zapcore.NewTee(cores...)
+	if redactor != nil {
+		// Entries are redacted once for all the sinks
+		core = newRedactCore(core, redactor)
+		built.redactor = redactor
+	}
 
-	// Prepare zap logger instance
-	unsugared := zap.New(core)
-
-	if config.Name != "" {
-		unsugared = unsugared.Named(strings.ReplaceAll(strings.ToLower(config.Name), " ", "_"))
+	// Prepare sampling and rate limiting
+	if counter != nil {
+		base := core
+		if config.Sampling != nil {
+			core = newSamplerCore(core, *config.Sampling, counter)
+		}
+		if config.RateLimit != nil {
+			core = newRateLimitCore(core, newRateLimiter(*config.RateLimit), counter)
+		}
+		reporter := newDropReportCore(core, base, counter)
+		// The reporter writes to the sinks, it is stopped before them
+		built.closers = append([]io.Closer{startDropReporter(reporter)}, built.closers...)
+		core = reporter
 	}
-	ll.unsugared = unsugared
-	ll.SugaredLogger = unsugared.Sugar()
 
-	return ll
+	built.core = core
+	return built, buildErr
+}
+
+// rootName returns the name of the logger built from the config.
+func rootName(config LoggerConfig) string {
+	return strings.ReplaceAll(strings.ToLower(config.Name), " ", "_")
+}
+
+// outputEncoding returns the encoding of the console or file output, honoring the deprecated Json flags.
+func outputEncoding(encoding Encoding, json bool) Encoding {
+	if encoding == TextEncoding && json {
+		return JSONEncoding
+	}
+	return encoding
 }
 
 // SetConsoleLevel sets the logging level for the console logger.
 func (l *logger) SetConsoleLevel(level LogLevel) {
-	var consoleLevel zapcore.Level
-	consoleLevel.Set(strings.ToLower(level.String()))
-	l.consoleAtomLvl.SetLevel(consoleLevel)
+	l.consoleAtomLvl.SetLevel(level.zapLevel())
 }
 
 // SetFileLevel sets the logging level for the file logger.
 func (l *logger) SetFileLevel(level LogLevel) {
-	var fileLevel zapcore.Level
-	fileLevel.Set(strings.ToLower(level.String()))
-	l.fileAtomLvl.SetLevel(fileLevel)
+	l.fileAtomLvl.SetLevel(level.zapLevel())
+}
+
+// ConsoleLevel returns the logging level of the console logger.
+func (l *logger) ConsoleLevel() LogLevel {
+	return fromZapLevel(l.consoleAtomLvl.Level())
+}
+
+// FileLevel returns the logging level of the file logger.
+func (l *logger) FileLevel() LogLevel {
+	return fromZapLevel(l.fileAtomLvl.Level())
+}
+
+// enabled reports whether entries at level are written by any output of l. Loggers implemented
+// outside of the package are assumed to write every level.
+func enabled(l Logger, level LogLevel) bool {
+	ll, ok := l.(*logger)
+	if !ok {
+		return true
+	}
+	return ll.unsugared.Core().Enabled(level.zapLevel())
+}
+
+// SetSinkLevel sets the logging level of the sink with the given name.
+func (l *logger) SetSinkLevel(name string, level LogLevel) error {
+	atomLvl, ok := l.sinkLvls[name]
+	if !ok {
+		return fmt.Errorf("unknown sink: %q", name)
+	}
+	atomLvl.SetLevel(level.zapLevel())
+	return nil
+}
+
+// SinkLevel returns the logging level of the sink with the given name.
+func (l *logger) SinkLevel(name string) (LogLevel, bool) {
+	atomLvl, ok := l.sinkLvls[name]
+	if !ok {
+		return 0, false
+	}
+	return fromZapLevel(atomLvl.Level()), true
+}
+
+// SetNamedLevel overrides the sink levels for the named logger and its descendants.
+func (l *logger) SetNamedLevel(name string, level LogLevel) {
+	l.overrides.set(name, level.zapLevel())
+}
+
+// UnsetNamedLevel removes the level override registered for the given name.
+func (l *logger) UnsetNamedLevel(name string) {
+	l.overrides.unset(name)
+}
+
+// NamedLevel returns the level override in effect for the logger with the given name.
+func (l *logger) NamedLevel(name string) (LogLevel, bool) {
+	level, ok := l.overrides.lookup(name)
+	return fromZapLevel(level), ok
+}
+
+// NamedLevels returns all registered level overrides keyed by logger name.
+func (l *logger) NamedLevels() map[string]LogLevel {
+	return l.overrides.all()
 }
 
 // Named adds a new path segment to the logger's name. Segments are joined by periods. By default, Loggers are unnamed.
 func (l *logger) Named(s string) Logger {
-	ll := l.unsugared.Named(s)
-	return &logger{
-		consoleAtomLvl: l.consoleAtomLvl,
-		fileAtomLvl:    l.fileAtomLvl,
-		unsugared:      ll,
-		SugaredLogger:  ll.Sugar(),
+	return l.derive(l.unsugared.Named(s))
+}
+
+// With returns a child logger carrying the given key-value pairs on every entry.
+func (l *logger) With(keysAndValues ...interface{}) Logger {
+	if len(keysAndValues) == 0 {
+		return l
 	}
+	return l.derive(l.SugaredLogger.With(keysAndValues...).Desugar())
+}
+
+// WithFields returns a child logger carrying the given strongly typed fields on every entry.
+func (l *logger) WithFields(fields ...Field) Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	return l.derive(l.unsugared.With(fields...))
+}
+
+// WithContext returns a child logger carrying the fields extracted from ctx.
+func (l *logger) WithContext(ctx context.Context) Logger {
+	return l.With(contextFields(ctx)...)
+}
+
+// DebugCtx logs a message at [DebugLevel] with the fields extracted from ctx and some additional context.
+func (l *logger) DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.skipped.Debugw(msg, append(contextFields(ctx), keysAndValues...)...)
+}
+
+// InfoCtx logs a message at [InfoLevel] with the fields extracted from ctx and some additional context.
+func (l *logger) InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.skipped.Infow(msg, append(contextFields(ctx), keysAndValues...)...)
+}
+
+// WarnCtx logs a message at [WarnLevel] with the fields extracted from ctx and some additional context.
+func (l *logger) WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.skipped.Warnw(msg, append(contextFields(ctx), keysAndValues...)...)
+}
+
+// ErrorCtx logs a message at [ErrorLevel] with the fields extracted from ctx and some additional context.
+func (l *logger) ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.skipped.Errorw(msg, append(contextFields(ctx), keysAndValues...)...)
+}
+
+// PanicCtx logs a message with the fields extracted from ctx and some additional context, then panics.
+func (l *logger) PanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.skipped.Panicw(msg, append(contextFields(ctx), keysAndValues...)...)
+}
+
+// FatalCtx logs a message with the fields extracted from ctx and some additional context, then calls os.Exit.
+func (l *logger) FatalCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.skipped.Fatalw(msg, append(contextFields(ctx), keysAndValues...)...)
+}
+
+// derive returns a child logger wrapping unsugared which shares the levels of l.
+func (l *logger) derive(unsugared *zap.Logger) *logger {
+	child := *l
+	child.setUnsugared(unsugared)
+	return &child
+}
+
+func (l *logger) setUnsugared(unsugared *zap.Logger) {
+	l.unsugared = unsugared
+	l.SugaredLogger = unsugared.Sugar()
+	l.skipped = unsugared.WithOptions(zap.AddCallerSkip(1)).Sugar()
+}
+
+// withCallerSkip returns a child logger reporting the caller skip frames further up the stack.
+// Wrappers such as the package-level helpers use it to report their own callers.
+func (l *logger) withCallerSkip(skip int) Logger {
+	return l.derive(l.unsugared.WithOptions(zap.AddCallerSkip(skip)))
 }
 
 // Sync calls the underlying loggers's Sync method, flushing any buffered log entries. Applications should take care to call Sync before exiting.
@@ -239,17 +605,49 @@ func (l *logger) Sync() error {
 	return l.unsugared.Sync()
 }
 
-func newRotateFile(config LoggerConfig) zapcore.WriteSyncer {
-	if err := fileutil.CreateFolders(config.LogDirectory); err != nil {
-		Errorf("could not create log directory. err: %v", err)
+// Rotate closes the log file, renames it with a timestamp and opens a new one. It does nothing
+// when the file output is disabled.
+func (l *logger) Rotate() error {
+	file := l.root.current.Load().file
+	if file == nil {
 		return nil
 	}
+	return file.Rotate()
+}
+
+// Close flushes the outputs and releases their resources: the log file, the background goroutines
+// of async writing and drop reports, and the connections of network and syslog sinks. Entries logged
+// afterwards are discarded. The outputs are shared with the derived loggers, closing any of them
+// closes them all.
+func (l *logger) Close() error {
+	return l.root.close()
+}
 
-	// Lumberjack.Logger is already safe for concurrent use, so we don't need to lock it.
-	return zapcore.AddSync(&lumberjack.Logger{
+func newRotateFile(config LoggerConfig) (*rotateFile, error) {
+	if err := fileutil.CreateFolders(config.LogDirectory); err != nil {
+		return nil, fmt.Errorf("could not create log directory. err: %v", err)
+	}
+
+	if err := checkWritable(config.LogDirectory); err != nil {
+		return nil, fmt.Errorf("log directory is not writable. err: %v", err)
+	}
+
+	return newRotateFileLogger(config, &lumberjack.Logger{
 		Filename:   filepath.Join(config.LogDirectory, config.Filename),
 		MaxSize:    config.MaxSize,
 		MaxAge:     config.MaxAge,
 		MaxBackups: config.MaxBackup,
-	})
+		Compress:   config.Compress,
+		LocalTime:  config.LocalTime,
+	}), nil
+}
+
+// checkWritable creates and removes a temporary file inside dir.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(fileutil.CleanPathOrDefault(dir, "."), ".logutil-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
 }
@@ -0,0 +1,160 @@
+package logutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLogger returns a logger writing JSON to a temporary file in place of stderr
+// and a function reading back the decoded entries.
+func newTestLogger(t *testing.T, config LoggerConfig) (Logger, func() []map[string]interface{}) {
+	t.Helper()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "console.log"))
+	require.Nil(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	stderr := os.Stderr
+	os.Stderr = f
+	config.ConsoleEnabled = true
+	config.ConsoleJson = true
+	l := NewLogger(config)
+	os.Stderr = stderr
+
+	return l, func() []map[string]interface{} {
+		require.Nil(t, l.Sync())
+		r, err := os.Open(f.Name())
+		require.Nil(t, err)
+		defer r.Close()
+
+		var entries []map[string]interface{}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			entry := make(map[string]interface{})
+			require.Nil(t, json.Unmarshal(scanner.Bytes(), &entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+func TestWith(t *testing.T) {
+	l, entries := newTestLogger(t, LoggerConfig{ConsoleLevel: InfoLevel})
+
+	child := l.With("component", "db").WithFields(Int("shard", 3))
+	child.Infow("connected", "host", "localhost")
+	l.Info("parent")
+
+	got := entries()
+	require.Len(t, got, 2)
+	require.Equal(t, "db", got[0]["component"])
+	require.Equal(t, float64(3), got[0]["shard"])
+	require.Equal(t, "localhost", got[0]["host"])
+	require.NotContains(t, got[1], "component")
+}
+
+func TestChildSharesLevels(t *testing.T) {
+	l, entries := newTestLogger(t, LoggerConfig{ConsoleLevel: InfoLevel})
+
+	child := l.With("component", "db").Named("pool")
+	child.Debug("hidden")
+	l.SetConsoleLevel(DebugLevel)
+	child.Debug("visible")
+
+	got := entries()
+	require.Len(t, got, 1)
+	require.Equal(t, "visible", got[0]["msg"])
+	require.Equal(t, "pool", got[0]["logger"])
+}
+
+func TestNamedLevels(t *testing.T) {
+	l, entries := newTestLogger(t, LoggerConfig{ConsoleLevel: InfoLevel})
+
+	named := l.(NamedLeveler)
+	named.SetNamedLevel("db", DebugLevel)
+	named.SetNamedLevel("db.pool.idle", ErrorLevel)
+
+	l.Named("db").Named("pool").Debug("pool debug")
+	l.Named("db").Named("pool").Named("idle").Warn("idle warn")
+	l.Named("dbx").Debug("dbx debug")
+	l.Named("http").Debug("http debug")
+	l.Named("http").Info("http info")
+
+	level, ok := named.NamedLevel("db.pool")
+	require.True(t, ok)
+	require.Equal(t, DebugLevel, level)
+	_, ok = named.NamedLevel("http")
+	require.False(t, ok)
+
+	named.UnsetNamedLevel("db")
+	l.Named("db").Debug("db debug")
+	require.Equal(t, map[string]LogLevel{"db.pool.idle": ErrorLevel}, named.NamedLevels())
+
+	var msgs []interface{}
+	for _, entry := range entries() {
+		msgs = append(msgs, entry["msg"])
+	}
+	require.Equal(t, []interface{}{"pool debug", "http info"}, msgs)
+}
+
+func TestClose(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Reads until the logger closes the connection
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	sink, err := NewNetworkSink("tcp", "tcp", listener.Addr().String(), InfoLevel, JSONEncoding)
+	require.Nil(t, err)
+
+	dir := t.TempDir()
+	l, err := NewLoggerE(LoggerConfig{
+		FileEnabled:  true,
+		LogDirectory: dir,
+		Filename:     "app.log",
+		Sinks:        []Sink{sink},
+		Sampling:     &SamplingConfig{Initial: 100},
+		Async:        &AsyncConfig{FlushInterval: time.Hour},
+	})
+	require.Nil(t, err)
+	require.Greater(t, runtime.NumGoroutine(), goroutines)
+
+	child := l.Named("child")
+	child.Info("queued")
+	require.Nil(t, l.(io.Closer).Close())
+	require.Nil(t, l.(io.Closer).Close())
+	child.Info("discarded")
+
+	require.Contains(t, <-received, `"msg":"queued"`)
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.Nil(t, err)
+	require.Contains(t, string(data), "queued")
+	require.NotContains(t, string(data), "discarded")
+
+	// lumberjack never stops the goroutine removing old backups
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > goroutines+1; {
+		require.True(t, time.Now().Before(deadline), "goroutines left running")
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.ErrorContains(t, l.(ConfigApplier).ApplyConfig(LoggerConfig{}), "logger is closed")
+}
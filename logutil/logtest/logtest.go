@@ -0,0 +1,251 @@
+// Package logtest provides loggers for testing code logging through a logutil.Logger.
+package logtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justmumu/goutils/logutil"
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is a recorded log entry.
+type Entry struct {
+	Level   logutil.LogLevel
+	Time    time.Time
+	Name    string
+	Message string
+	// Fields holds the fields of the logger and the entry, as encoded by zapcore.MapObjectEncoder
+	Fields map[string]interface{}
+}
+
+// Entries is a list of recorded entries, filtering returns the matching ones in order.
+type Entries []Entry
+
+// Filter returns the entries matching fn.
+func (e Entries) Filter(fn func(Entry) bool) Entries {
+	var filtered Entries
+	for _, entry := range e {
+		if fn(entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// Level returns the entries logged at level.
+func (e Entries) Level(level logutil.LogLevel) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return entry.Level == level
+	})
+}
+
+// AtLeast returns the entries logged at level or above.
+func (e Entries) AtLeast(level logutil.LogLevel) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return entry.Level >= level
+	})
+}
+
+// Name returns the entries logged by the logger named name.
+func (e Entries) Name(name string) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return entry.Name == name
+	})
+}
+
+// Message returns the entries with the message msg.
+func (e Entries) Message(msg string) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return entry.Message == msg
+	})
+}
+
+// MessageContains returns the entries whose message contains substr.
+func (e Entries) MessageContains(substr string) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return strings.Contains(entry.Message, substr)
+	})
+}
+
+// HasField returns the entries with the field key.
+func (e Entries) HasField(key string) Entries {
+	return e.Filter(func(entry Entry) bool {
+		_, ok := entry.Fields[key]
+		return ok
+	})
+}
+
+// Field returns the entries with the field key equal to value. Values are compared in their
+// default formats, so Field("n", 3) matches fields logged as int, int64 or uint.
+func (e Entries) Field(key string, value interface{}) Entries {
+	want := fmt.Sprint(value)
+	return e.Filter(func(entry Entry) bool {
+		got, ok := entry.Fields[key]
+		return ok && fmt.Sprint(got) == want
+	})
+}
+
+// Len returns the number of entries.
+func (e Entries) Len() int {
+	return len(e)
+}
+
+// Messages returns the messages of the entries.
+func (e Entries) Messages() []string {
+	messages := make([]string, 0, len(e))
+	for _, entry := range e {
+		messages = append(messages, entry.Message)
+	}
+	return messages
+}
+
+// AssertCount reports a test error listing the entries unless there are n of them.
+func (e Entries) AssertCount(tb testing.TB, n int) bool {
+	tb.Helper()
+	if len(e) == n {
+		return true
+	}
+
+	lines := make([]string, 0, len(e))
+	for _, entry := range e {
+		lines = append(lines, fmt.Sprintf("\t%s %q %s %v", entry.Level, entry.Message, entry.Name, entry.Fields))
+	}
+	tb.Errorf("expected %d log entries, got %d:\n%s", n, len(e), strings.Join(lines, "\n"))
+	return false
+}
+
+// Recorder keeps the entries of a logger created by NewRecorder in memory.
+type Recorder struct {
+	mu      sync.Mutex
+	entries Entries
+}
+
+// NewRecorder returns a Logger recording the entries at level or above.
+func NewRecorder(level logutil.LogLevel) (logutil.Logger, *Recorder) {
+	recorder := &Recorder{}
+	logger := logutil.NewLogger(logutil.LoggerConfig{
+		Sinks: []logutil.Sink{logutil.NewCoreSink("recorder", level, &recorderCore{recorder: recorder})},
+	})
+	return logger, recorder
+}
+
+// Entries returns the recorded entries.
+func (r *Recorder) Entries() Entries {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append(Entries(nil), r.entries...)
+}
+
+// Len returns the number of recorded entries.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// TakeAll returns the recorded entries and resets the recorder.
+func (r *Recorder) TakeAll() Entries {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := r.entries
+	r.entries = nil
+	return entries
+}
+
+// Reset removes the recorded entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+func (r *Recorder) add(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// recorderCore records the entries in its recorder.
+type recorderCore struct {
+	recorder *Recorder
+	fields   []zapcore.Field
+}
+
+func (c *recorderCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *recorderCore) With(fields []zapcore.Field) zapcore.Core {
+	return &recorderCore{
+		recorder: c.recorder,
+		fields:   append(append([]zapcore.Field(nil), c.fields...), fields...),
+	}
+}
+
+func (c *recorderCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *recorderCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	c.recorder.add(Entry{
+		Level:   fromZapLevel(ent.Level),
+		Time:    ent.Time,
+		Name:    ent.LoggerName,
+		Message: ent.Message,
+		Fields:  enc.Fields,
+	})
+	return nil
+}
+
+func (c *recorderCore) Sync() error {
+	return nil
+}
+
+func fromZapLevel(level zapcore.Level) logutil.LogLevel {
+	switch level {
+	case zapcore.DebugLevel:
+		return logutil.DebugLevel
+	case zapcore.InfoLevel:
+		return logutil.InfoLevel
+	case zapcore.WarnLevel:
+		return logutil.WarnLevel
+	case zapcore.ErrorLevel:
+		return logutil.ErrorLevel
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return logutil.PanicLevel
+	default:
+		return logutil.FatalLevel
+	}
+}
+
+// New returns a Logger writing the entries at level or above to tb.Log, so they are shown
+// for failed tests and verbose runs.
+func New(tb testing.TB, level logutil.LogLevel) logutil.Logger {
+	return logutil.NewLogger(logutil.LoggerConfig{
+		Sinks:         []logutil.Sink{logutil.NewWriterSink("test", tbWriter{tb: tb}, level, logutil.TextEncoding)},
+		CallerEnabled: true,
+	})
+}
+
+// tbWriter writes each encoded entry as a test log line.
+type tbWriter struct {
+	tb testing.TB
+}
+
+func (w tbWriter) Write(p []byte) (int, error) {
+	w.tb.Helper()
+	w.tb.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
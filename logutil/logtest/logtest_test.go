@@ -0,0 +1,79 @@
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/justmumu/goutils/logutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	logger, recorder := NewRecorder(logutil.InfoLevel)
+
+	logger.Debugw("hidden")
+	logger.With("component", "db").Infow("connected", "attempt", 2)
+	logger.Named("http").Warnw("slow request", "path", "/users")
+	logger.Errorw("query failed", "attempt", 3)
+	logger.InfoCtx(logutil.ContextWithFields(context.Background(), "request_id", "abc"), "handled")
+
+	entries := recorder.Entries()
+	entries.AssertCount(t, 4)
+	require.Equal(t, []string{"connected", "slow request", "query failed", "handled"}, entries.Messages())
+
+	require.Equal(t, 1, entries.Level(logutil.WarnLevel).Len())
+	require.Equal(t, 2, entries.AtLeast(logutil.WarnLevel).Len())
+	require.Equal(t, "http", entries.Message("slow request")[0].Name)
+	require.Equal(t, 1, entries.Name("http").Len())
+	require.Equal(t, 1, entries.MessageContains("fail").Len())
+	require.Equal(t, 2, entries.HasField("attempt").Len())
+	require.Equal(t, []string{"connected"}, entries.Field("attempt", 2).Field("component", "db").Messages())
+	require.Equal(t, 1, entries.Field("request_id", "abc").Len())
+
+	require.Len(t, recorder.TakeAll(), 4)
+	require.Zero(t, recorder.Len())
+
+	require.Nil(t, logger.(logutil.SinkLeveler).SetSinkLevel("recorder", logutil.DebugLevel))
+	logger.Debug("visible")
+	require.Equal(t, 1, recorder.Len())
+	recorder.Reset()
+	require.Zero(t, recorder.Len())
+}
+
+func TestAssertCount(t *testing.T) {
+	logger, recorder := NewRecorder(logutil.DebugLevel)
+	logger.Info("one")
+
+	ftb := &fakeTB{TB: t}
+	require.False(t, recorder.Entries().AssertCount(ftb, 2))
+	require.Contains(t, ftb.errors[0], "expected 2 log entries, got 1")
+	require.Contains(t, ftb.errors[0], `"one"`)
+}
+
+func TestNew(t *testing.T) {
+	ftb := &fakeTB{TB: t}
+	logger := New(ftb, logutil.InfoLevel)
+
+	logger.Debug("hidden")
+	logger.Infow("hello", "n", 1)
+
+	require.Len(t, ftb.logs, 1)
+	require.Regexp(t, `^\S+\tINFO\tlogtest/logtest_test\.go:\d+\thello\t\{"n": 1\}$`, ftb.logs[0])
+}
+
+type fakeTB struct {
+	testing.TB
+	logs   []string
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Log(args ...interface{}) {
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,113 @@
+package logutil
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// levelOverrides is a registry of levels keyed by logger names. An override applies to the
+// logger with the exact name and to all of its descendants, the longest matching name wins.
+type levelOverrides struct {
+	mu     sync.RWMutex
+	levels map[string]zapcore.Level
+	// count mirrors len(levels) so the hot path can skip locking when there are no overrides
+	count atomic.Int32
+}
+
+func newLevelOverrides() *levelOverrides {
+	return &levelOverrides{levels: make(map[string]zapcore.Level)}
+}
+
+func (o *levelOverrides) set(name string, level zapcore.Level) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.levels[name] = level
+	o.count.Store(int32(len(o.levels)))
+}
+
+func (o *levelOverrides) unset(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.levels, name)
+	o.count.Store(int32(len(o.levels)))
+}
+
+// lookup returns the level of the longest registered name which is name itself or one of its ancestors.
+func (o *levelOverrides) lookup(name string) (zapcore.Level, bool) {
+	if o.count.Load() == 0 {
+		return 0, false
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	for {
+		if level, ok := o.levels[name]; ok {
+			return level, true
+		}
+		idx := strings.LastIndexByte(name, '.')
+		if idx < 0 {
+			return 0, false
+		}
+		name = name[:idx]
+	}
+}
+
+// enabled reports whether any override lets entries at the given level through.
+func (o *levelOverrides) enabled(level zapcore.Level) bool {
+	if o.count.Load() == 0 {
+		return false
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	for _, l := range o.levels {
+		if level >= l {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *levelOverrides) all() map[string]LogLevel {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	levels := make(map[string]LogLevel, len(o.levels))
+	for name, level := range o.levels {
+		levels[name] = fromZapLevel(level)
+	}
+	return levels
+}
+
+// overrideCore gates the wrapped core by the level of the output, unless the entry's logger
+// name has an override. The wrapped core must accept every level.
+type overrideCore struct {
+	zapcore.Core
+	level     zapcore.LevelEnabler
+	overrides *levelOverrides
+}
+
+func newOverrideCore(core zapcore.Core, level zapcore.LevelEnabler, overrides *levelOverrides) zapcore.Core {
+	return &overrideCore{Core: core, level: level, overrides: overrides}
+}
+
+func (c *overrideCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level) || c.overrides.enabled(level)
+}
+
+func (c *overrideCore) With(fields []zapcore.Field) zapcore.Core {
+	return newOverrideCore(c.Core.With(fields), c.level, c.overrides)
+}
+
+func (c *overrideCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if level, ok := c.overrides.lookup(ent.LoggerName); ok {
+		if ent.Level < level {
+			return ce
+		}
+	} else if !c.level.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
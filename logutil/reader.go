@@ -0,0 +1,560 @@
+package logutil
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justmumu/goutils/fileutil"
+)
+
+// followPollInterval is how often FollowLog checks the file for new entries
+const followPollInterval = 250 * time.Millisecond
+
+var (
+	callerPattern = regexp.MustCompile(`^\S+\.go:\d+$`)
+	ansiPattern   = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	logfmtPattern = regexp.MustCompile(`^[^\s="]+=`)
+)
+
+// LogEntry is an entry read back from a log file.
+type LogEntry struct {
+	Time       time.Time
+	Level      LogLevel
+	Name       string
+	Message    string
+	Caller     string
+	Stacktrace string
+	// Fields holds the remaining fields. Numbers are json.Number values, the scalar values of
+	// logfmt entries are strings.
+	Fields map[string]interface{}
+}
+
+// Field returns the value of the field key. Dotted keys reach into the objects of JSON and text
+// entries, e.g. "req.id".
+func (e LogEntry) Field(key string) (interface{}, bool) {
+	if value, ok := e.Fields[key]; ok {
+		return value, true
+	}
+
+	fields := e.Fields
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		value, ok := fields[part]
+		if !ok {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return value, true
+		}
+		if fields, ok = value.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// LogQuery selects the entries read from log files. The zero value matches every entry.
+type LogQuery struct {
+	// Since excludes the entries logged before it when set
+	Since time.Time
+	// Until excludes the entries logged after it when set
+	Until time.Time
+	// MinLevel excludes the entries logged below it
+	MinLevel LogLevel
+	// Name matches the entries of the named logger and its children
+	Name string
+	// Fields matches the entries having every field with the given value, compared in text form
+	Fields map[string]string
+	// TimeFormat is the time format of the entries when they don't use ISO8601, RFC3339 or
+	// epoch timestamps, see the TimeFormat options of LoggerConfig.
+	TimeFormat string
+}
+
+// Match reports whether the entry is selected by the query.
+func (q LogQuery) Match(entry LogEntry) bool {
+	if !q.Since.IsZero() && entry.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && entry.Time.After(q.Until) {
+		return false
+	}
+	if entry.Level < q.MinLevel {
+		return false
+	}
+	if q.Name != "" && entry.Name != q.Name && !strings.HasPrefix(entry.Name, q.Name+".") {
+		return false
+	}
+	for key, want := range q.Fields {
+		value, ok := entry.Field(key)
+		if !ok || fmt.Sprint(value) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// LogFiles returns the log file of the config preceded by its rotated backups, oldest first.
+// Compressed backups are included, the log file is omitted when it doesn't exist.
+func LogFiles(config LoggerConfig) ([]string, error) {
+	if config.Filename == "" {
+		return nil, errors.New("filename is required")
+	}
+	dir := fileutil.CleanPathOrDefault(config.LogDirectory, ".")
+
+	paths, err := logBackups(dir, config.Filename)
+	if err != nil {
+		return nil, err
+	}
+	current := filepath.Join(dir, config.Filename)
+	if _, err := os.Stat(current); err == nil {
+		paths = append(paths, current)
+	}
+	return paths, nil
+}
+
+// ReadLogs calls fn with the entries of the files matching the query, in order. Files ending with
+// ".gz" are decompressed. Lines which aren't entries are skipped, except the stack traces following
+// text entries. Reading stops at the first error returned by fn.
+func ReadLogs(paths []string, query LogQuery, fn func(LogEntry) error) error {
+	for _, path := range paths {
+		if err := readLogFile(path, query, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readLogFile(path string, query LogQuery, fn func(LogEntry) error) error {
+	f, err := fileutil.SafeOpen(path)
+	if err != nil {
+		return fmt.Errorf("could not open log file. err: %v", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("could not decompress log file %s. err: %v", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	p := &entryParser{query: query, fn: fn}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			if err := p.line(line); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return p.flush()
+		}
+		if err != nil {
+			return fmt.Errorf("could not read log file %s. err: %v", path, err)
+		}
+	}
+}
+
+// FollowLog calls fn with the entries matching the query as they are appended to the file, until
+// ctx is done. The existing entries are read first unless fromEnd is set. The file is reopened when
+// it is rotated and read from the start when it is truncated.
+func FollowLog(ctx context.Context, path string, query LogQuery, fromEnd bool, fn func(LogEntry) error) error {
+	f, err := fileutil.SafeOpen(path)
+	if err != nil {
+		return fmt.Errorf("could not open log file. err: %v", err)
+	}
+	defer func() { f.Close() }()
+
+	if fromEnd {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			return fmt.Errorf("could not seek log file. err: %v", err)
+		}
+	}
+
+	ticker := time.NewTicker(followPollInterval)
+	defer ticker.Stop()
+
+	p := &entryParser{query: query, fn: fn}
+	br := bufio.NewReader(f)
+	var partial string
+	for {
+		line, err := br.ReadString('\n')
+		if err == nil {
+			if err := p.line(partial + line); err != nil {
+				return err
+			}
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			return fmt.Errorf("could not read log file. err: %v", err)
+		}
+		// Keep the incomplete line until the rest is written
+		partial += line
+
+		// Text entries may still be followed by their stack trace, but emitting them late would
+		// hold back the entry until the next one is written
+		if err := p.flush(); err != nil {
+			return err
+		}
+
+		rotated, truncated, err := checkFollowed(f, path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case rotated:
+			next, err := fileutil.SafeOpen(path)
+			if err != nil {
+				return fmt.Errorf("could not reopen log file. err: %v", err)
+			}
+			// Entries may have been written to the old file since it was last read
+			if err := drainFollowed(br, p, partial); err != nil {
+				next.Close()
+				return err
+			}
+			partial = ""
+			f.Close()
+			f = next
+			br.Reset(f)
+			continue
+		case truncated:
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("could not seek log file. err: %v", err)
+			}
+			br.Reset(f)
+			partial = ""
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// drainFollowed passes the lines left in br to p, starting with partial, before the followed file
+// is replaced. The last line is passed even when incomplete, the rest of it is never written.
+func drainFollowed(br *bufio.Reader, p *entryParser, partial string) error {
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("could not read log file. err: %v", err)
+		}
+		if err := p.line(partial + line); err != nil {
+			return err
+		}
+		partial = ""
+		if err == io.EOF {
+			return p.flush()
+		}
+	}
+}
+
+// checkFollowed reports whether the followed file was replaced by a new file at path or truncated
+// below the read offset.
+func checkFollowed(f *os.File, path string) (rotated, truncated bool, err error) {
+	info, err := f.Stat()
+	if err != nil {
+		return false, false, fmt.Errorf("could not stat log file. err: %v", err)
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		// The new file is not created yet
+		return false, false, nil
+	}
+	if !os.SameFile(info, current) {
+		return true, false, nil
+	}
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, false, fmt.Errorf("could not seek log file. err: %v", err)
+	}
+	return false, info.Size() < offset, nil
+}
+
+// entryParser parses lines to entries, holding back text entries until their stack trace is read.
+type entryParser struct {
+	query   LogQuery
+	fn      func(LogEntry) error
+	pending *LogEntry
+	stack   []string
+}
+
+func (p *entryParser) line(line string) error {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil
+	}
+
+	entry, err := ParseLogLine(line, p.query.TimeFormat)
+	if err != nil {
+		if p.pending != nil && p.pending.Stacktrace == "" && detectEncoding(line) == TextEncoding {
+			p.stack = append(p.stack, line)
+		}
+		return nil
+	}
+
+	if err := p.flush(); err != nil {
+		return err
+	}
+	if detectEncoding(line) == TextEncoding {
+		p.pending = &entry
+		return nil
+	}
+	return p.emit(entry)
+}
+
+func (p *entryParser) flush() error {
+	if p.pending == nil {
+		return nil
+	}
+	entry := *p.pending
+	if len(p.stack) > 0 {
+		entry.Stacktrace = strings.Join(p.stack, "\n")
+	}
+	p.pending = nil
+	p.stack = nil
+	return p.emit(entry)
+}
+
+func (p *entryParser) emit(entry LogEntry) error {
+	if !p.query.Match(entry) {
+		return nil
+	}
+	return p.fn(entry)
+}
+
+// detectEncoding returns the encoding of a log line.
+func detectEncoding(line string) Encoding {
+	switch {
+	case strings.HasPrefix(line, "{"):
+		return JSONEncoding
+	case logfmtPattern.MatchString(line):
+		return LogfmtEncoding
+	default:
+		return TextEncoding
+	}
+}
+
+// ParseLogLine parses a line written with any Encoding. The stack trace of text entries is written
+// on the following lines and is not part of the entry. timeFormat is only needed for custom layouts,
+// see LogQuery.
+func ParseLogLine(line, timeFormat string) (LogEntry, error) {
+	switch detectEncoding(line) {
+	case JSONEncoding:
+		return parseJSONLine(line, timeFormat)
+	case LogfmtEncoding:
+		return parseLogfmtLine(line, timeFormat)
+	default:
+		return parseTextLine(line, timeFormat)
+	}
+}
+
+func parseJSONLine(line, timeFormat string) (LogEntry, error) {
+	fields, err := decodeObject(line)
+	if err != nil {
+		return LogEntry{}, fmt.Errorf("could not decode log line. err: %v", err)
+	}
+
+	var entry LogEntry
+	for key, value := range fields {
+		s := fmt.Sprint(value)
+		switch key {
+		case "ts":
+			t, err := parseLogTime(s, timeFormat)
+			if err != nil {
+				return LogEntry{}, err
+			}
+			entry.Time = t
+		case "level":
+			level, err := ParseLogLevel(s)
+			if err != nil {
+				return LogEntry{}, err
+			}
+			entry.Level = level
+		case "logger":
+			entry.Name = s
+		case "msg":
+			entry.Message = s
+		case "caller":
+			entry.Caller = s
+		case "stacktrace":
+			entry.Stacktrace = s
+		default:
+			continue
+		}
+		delete(fields, key)
+	}
+	if entry.Time.IsZero() {
+		return LogEntry{}, errors.New("log line has no time")
+	}
+	entry.Fields = fields
+	return entry, nil
+}
+
+func parseLogfmtLine(line, timeFormat string) (LogEntry, error) {
+	entry := LogEntry{Fields: map[string]interface{}{}}
+	var hasLevel bool
+	for rest := line; rest != ""; {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return LogEntry{}, fmt.Errorf("invalid logfmt pair: %q", rest)
+		}
+		key := rest[:eq]
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return LogEntry{}, fmt.Errorf("invalid logfmt value of %s. err: %v", key, err)
+			}
+			rest = rest[len(quoted):]
+			value, _ = strconv.Unquote(quoted)
+		} else if sp := strings.IndexByte(rest, ' '); sp >= 0 {
+			value, rest = rest[:sp], rest[sp:]
+		} else {
+			value, rest = rest, ""
+		}
+		rest = strings.TrimLeft(rest, " ")
+
+		switch key {
+		case "ts":
+			t, err := parseLogTime(value, timeFormat)
+			if err != nil {
+				return LogEntry{}, err
+			}
+			entry.Time = t
+		case "level":
+			level, err := ParseLogLevel(value)
+			if err != nil {
+				return LogEntry{}, err
+			}
+			entry.Level = level
+			hasLevel = true
+		case "logger":
+			entry.Name = value
+		case "msg":
+			entry.Message = value
+		case "caller":
+			entry.Caller = value
+		case "stacktrace":
+			entry.Stacktrace = value
+		default:
+			entry.Fields[key] = logfmtValue(value)
+		}
+	}
+	if entry.Time.IsZero() || !hasLevel {
+		return LogEntry{}, errors.New("log line has no time or level")
+	}
+	return entry, nil
+}
+
+// logfmtValue decodes the objects written as JSON by the logfmt encoder, other values are kept as text.
+func logfmtValue(value string) interface{} {
+	if strings.HasPrefix(value, "{") {
+		if object, err := decodeObject(value); err == nil {
+			return object
+		}
+	}
+	return value
+}
+
+// decodeObject decodes a JSON object, keeping numbers as json.Number.
+func decodeObject(s string) (map[string]interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var object map[string]interface{}
+	if err := dec.Decode(&object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// parseTextLine parses the tab separated time, level, logger name, caller and message of a text
+// entry, followed by its fields as a JSON object.
+func parseTextLine(line, timeFormat string) (LogEntry, error) {
+	parts := strings.Split(line, "\t")
+	if len(parts) < 3 {
+		return LogEntry{}, errors.New("log line is not a text entry")
+	}
+
+	var entry LogEntry
+	t, err := parseLogTime(parts[0], timeFormat)
+	if err != nil {
+		return LogEntry{}, err
+	}
+	entry.Time = t
+	if entry.Level, err = ParseLogLevel(ansiPattern.ReplaceAllString(parts[1], "")); err != nil {
+		return LogEntry{}, err
+	}
+
+	rest := parts[2:]
+	if last := rest[len(rest)-1]; len(rest) > 1 && strings.HasPrefix(last, "{") {
+		if fields, err := decodeObject(last); err == nil {
+			entry.Fields, rest = fields, rest[:len(rest)-1]
+		}
+	}
+
+	// The logger name and caller are omitted when empty
+	if len(rest) > 1 && callerPattern.MatchString(rest[0]) {
+		entry.Caller, rest = rest[0], rest[1:]
+	} else if len(rest) > 1 {
+		entry.Name, rest = rest[0], rest[1:]
+		if len(rest) > 1 && callerPattern.MatchString(rest[0]) {
+			entry.Caller, rest = rest[0], rest[1:]
+		}
+	}
+	entry.Message = strings.Join(rest, "\t")
+	return entry, nil
+}
+
+// parseLogTime parses the time of an entry. Epoch timestamps are told apart by their magnitude
+// unless timeFormat names their unit.
+func parseLogTime(s, timeFormat string) (time.Time, error) {
+	switch strings.ToLower(timeFormat) {
+	case "", TimeFormatISO8601, TimeFormatRFC3339, TimeFormatRFC3339Nano, TimeFormatEpoch,
+		TimeFormatEpochMillis, TimeFormatEpochNanos:
+	default:
+		if t, err := time.Parse(timeFormat, s); err == nil {
+			return t, nil
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05.000Z0700", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse log time: %q", s)
+	}
+	unit := float64(time.Second)
+	switch format := strings.ToLower(timeFormat); {
+	case format == TimeFormatEpochNanos || format == "" && f > 1e16:
+		unit = 1
+	case format == TimeFormatEpochMillis || format == "" && f > 1e11:
+		unit = float64(time.Millisecond)
+	}
+	return time.Unix(0, int64(f*unit)), nil
+}
@@ -0,0 +1,242 @@
+package logutil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// collectLogs returns the entries of the files matching the query.
+func collectLogs(t *testing.T, paths []string, query LogQuery) []LogEntry {
+	t.Helper()
+	var entries []LogEntry
+	require.Nil(t, ReadLogs(paths, query, func(entry LogEntry) error {
+		entries = append(entries, entry)
+		return nil
+	}))
+	return entries
+}
+
+func TestReadLogsEncodings(t *testing.T) {
+	for _, encoding := range []Encoding{JSONEncoding, TextEncoding, LogfmtEncoding} {
+		t.Run(encoding.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			l, err := NewLoggerE(LoggerConfig{
+				CallerEnabled:     true,
+				StacktraceEnabled: true,
+				StacktraceLevel:   ErrorLevel,
+				Sinks:             []Sink{NewWriterSink("buf", &buf, DebugLevel, encoding)},
+			})
+			require.Nil(t, err)
+
+			l.Named("db").Debugw("query", "rows", 3)
+			l.Named("http").With("req", map[string]interface{}{"id": "r1"}).Infow("request\tserved", "status", 200)
+			l.Errorw("failed", "err", "boom")
+
+			path := filepath.Join(t.TempDir(), "app.log")
+			require.Nil(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+			entries := collectLogs(t, []string{path}, LogQuery{})
+			require.Len(t, entries, 3)
+			require.Equal(t, DebugLevel, entries[0].Level)
+			require.Equal(t, "db", entries[0].Name)
+			require.Equal(t, "query", entries[0].Message)
+			require.Contains(t, entries[0].Caller, "logutil/reader_test.go:")
+			require.WithinDuration(t, time.Now(), entries[0].Time, time.Minute)
+			require.Empty(t, entries[0].Stacktrace)
+			require.Equal(t, "failed", entries[2].Message)
+			require.Contains(t, entries[2].Stacktrace, "logutil.TestReadLogsEncodings")
+
+			entries = collectLogs(t, []string{path}, LogQuery{MinLevel: InfoLevel, Fields: map[string]string{"status": "200"}})
+			require.Len(t, entries, 1)
+			require.Equal(t, "http", entries[0].Name)
+			value, ok := entries[0].Field("req.id")
+			require.True(t, ok)
+			require.Equal(t, "r1", value)
+		})
+	}
+}
+
+func TestLogQuery(t *testing.T) {
+	now := time.Now()
+	entry := LogEntry{
+		Time:   now,
+		Level:  WarnLevel,
+		Name:   "app.db",
+		Fields: map[string]interface{}{"user": map[string]interface{}{"id": 7}},
+	}
+
+	require.True(t, LogQuery{}.Match(entry))
+	require.True(t, LogQuery{Name: "app"}.Match(entry))
+	require.False(t, LogQuery{Name: "ap"}.Match(entry))
+	require.True(t, LogQuery{MinLevel: WarnLevel}.Match(entry))
+	require.False(t, LogQuery{MinLevel: ErrorLevel}.Match(entry))
+	require.True(t, LogQuery{Since: now.Add(-time.Second), Until: now.Add(time.Second)}.Match(entry))
+	require.False(t, LogQuery{Since: now.Add(time.Second)}.Match(entry))
+	require.False(t, LogQuery{Until: now.Add(-time.Second)}.Match(entry))
+	require.True(t, LogQuery{Fields: map[string]string{"user.id": "7"}}.Match(entry))
+	require.False(t, LogQuery{Fields: map[string]string{"user.id": "8"}}.Match(entry))
+	require.False(t, LogQuery{Fields: map[string]string{"missing": ""}}.Match(entry))
+}
+
+func TestParseLogTime(t *testing.T) {
+	want := time.Date(2024, 5, 6, 7, 8, 9, 123000000, time.UTC)
+	for _, s := range []string{"2024-05-06T07:08:09.123Z", "2024-05-06T07:08:09.123+0000", "1714979289.123", "1714979289123", "1714979289123000000"} {
+		got, err := parseLogTime(s, "")
+		require.Nil(t, err, s)
+		require.WithinDuration(t, want, got, time.Millisecond, s)
+	}
+
+	got, err := parseLogTime("06/05/2024 07:08:09", "02/01/2006 15:04:05")
+	require.Nil(t, err)
+	require.True(t, want.Truncate(time.Second).Equal(got))
+
+	_, err = parseLogTime("yesterday", "")
+	require.NotNil(t, err)
+}
+
+func TestLogFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	line := func(msg string) string {
+		return `{"level":"info","ts":"2024-05-06T07:08:09.123Z","msg":"` + msg + `"}` + "\n"
+	}
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write([]byte(line("oldest")))
+	require.Nil(t, err)
+	require.Nil(t, zw.Close())
+
+	write("app-2024-05-06T07-08-09.123.log.gz", gz.String())
+	write("app-2024-05-07T07-08-09.123.log", line("older"))
+	write("app.log", line("current"))
+	write("app-other.log", line("unrelated"))
+	write("other.log", line("unrelated"))
+
+	paths, err := LogFiles(LoggerConfig{LogDirectory: dir, Filename: "app.log"})
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "app-2024-05-06T07-08-09.123.log.gz"),
+		filepath.Join(dir, "app-2024-05-07T07-08-09.123.log"),
+		filepath.Join(dir, "app.log"),
+	}, paths)
+
+	var messages []string
+	for _, entry := range collectLogs(t, paths, LogQuery{}) {
+		messages = append(messages, entry.Message)
+	}
+	require.Equal(t, []string{"oldest", "older", "current"}, messages)
+
+	stop := errors.New("stop")
+	var n int
+	err = ReadLogs(paths, LogQuery{}, func(LogEntry) error {
+		n++
+		return stop
+	})
+	require.Equal(t, stop, err)
+	require.Equal(t, 1, n)
+}
+
+func TestLogFilesOfFileSink(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLoggerE(LoggerConfig{
+		FileEnabled:  true,
+		FileLevel:    DebugLevel,
+		LogDirectory: dir,
+		Filename:     "app.log",
+	})
+	require.Nil(t, err)
+
+	l.Info("before rotation")
+	require.Nil(t, l.(Rotator).Rotate())
+	l.Info("after rotation")
+	_ = l.Sync()
+
+	paths, err := LogFiles(LoggerConfig{LogDirectory: dir, Filename: "app.log"})
+	require.Nil(t, err)
+	require.Len(t, paths, 2)
+
+	entries := collectLogs(t, paths, LogQuery{})
+	require.Len(t, entries, 2)
+	require.Equal(t, "before rotation", entries[0].Message)
+	require.Equal(t, "after rotation", entries[1].Message)
+}
+
+func TestFollowLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	line := func(level, msg string) string {
+		return `{"level":"` + level + `","ts":"2024-05-06T07:08:09.123Z","msg":"` + msg + `"}` + "\n"
+	}
+	require.Nil(t, os.WriteFile(path, []byte(line("info", "existing")), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries := make(chan LogEntry, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- FollowLog(ctx, path, LogQuery{MinLevel: InfoLevel}, true, func(entry LogEntry) error {
+			entries <- entry
+			return nil
+		})
+	}()
+	next := func() string {
+		select {
+		case entry := <-entries:
+			return entry.Message
+		case <-time.After(5 * time.Second):
+			t.Fatal("no entry followed")
+			return ""
+		}
+	}
+
+	// Give FollowLog the time to seek to the end
+	time.Sleep(2 * followPollInterval)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.Nil(t, err)
+	_, err = f.WriteString(line("debug", "filtered") + line("info", "appended") + `{"level":"info",`)
+	require.Nil(t, err)
+	require.Equal(t, "appended", next())
+
+	_, err = f.WriteString(`"ts":"2024-05-06T07:08:09.123Z","msg":"completed"}` + "\n")
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+	require.Equal(t, "completed", next())
+
+	require.Nil(t, os.Rename(path, path+".1"))
+	require.Nil(t, os.WriteFile(path, []byte(line("warn", "rotated")), 0o600))
+	require.Equal(t, "rotated", next())
+
+	// Truncation is noticed once the file is shorter than the read offset
+	require.Nil(t, os.WriteFile(path, []byte(line("info", "trunc")), 0o600))
+	require.Equal(t, "trunc", next())
+
+	cancel()
+	require.Nil(t, <-done)
+}
+
+func TestDrainFollowed(t *testing.T) {
+	// The entries written to the old file after the last read are passed before switching files
+	line := `{"level":"info","ts":"2024-05-06T07:08:09.123Z","msg":"late"}` + "\n"
+	var messages []string
+	p := &entryParser{fn: func(entry LogEntry) error {
+		messages = append(messages, entry.Message)
+		return nil
+	}}
+	br := bufio.NewReader(strings.NewReader(`"ts":"2024-05-06T07:08:09.123Z","msg":"split"}` + "\n" + line))
+	require.Nil(t, drainFollowed(br, p, `{"level":"info",`))
+	require.Equal(t, []string{"split", "late"}, messages)
+}
@@ -0,0 +1,297 @@
+package logutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultRedactionMask replaces the redacted values when no mask is configured.
+const DefaultRedactionMask = "[REDACTED]"
+
+// Value patterns of common secrets for RedactionConfig.ValuePatterns.
+const (
+	// BearerTokenPattern matches bearer tokens such as the ones of Authorization headers.
+	BearerTokenPattern = `(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*`
+	// CardNumberPattern matches payment card numbers of 13 to 19 digits, optionally grouped by spaces or dashes.
+	CardNumberPattern = `\b\d(?:[ -]?\d){12,18}\b`
+)
+
+// RedactionConfig configures the redaction of sensitive values before entries are encoded.
+type RedactionConfig struct {
+	// Keys are the case-insensitive names of fields and nested map keys whose values are masked, e.g. "password"
+	Keys []string `json:"keys" yaml:"keys" env:"KEYS"`
+	// KeyPatterns are case-insensitive path.Match patterns masking the values of the matching keys. Patterns
+	// are matched against the key and its dotted path starting with the field key, e.g. "*token*" or "req.headers.*"
+	KeyPatterns []string `json:"key_patterns" yaml:"key_patterns" env:"KEY_PATTERNS"`
+	// ValuePatterns are regular expressions whose matches are masked in messages, string values and
+	// byte slices, binary values are matched before their base64 encoding.
+	// Patterns containing commas can't be set from the environment.
+	ValuePatterns []string `json:"value_patterns" yaml:"value_patterns" env:"VALUE_PATTERNS"`
+	// Mask replaces the redacted values, defaults to DefaultRedactionMask
+	Mask string `json:"mask" yaml:"mask" env:"MASK"`
+}
+
+// redactor applies a RedactionConfig.
+type redactor struct {
+	keys     map[string]struct{}
+	patterns []string
+	values   []*regexp.Regexp
+	mask     string
+}
+
+// newRedactor compiles config. Invalid patterns are reported and left out of the returned redactor.
+func newRedactor(config RedactionConfig) (*redactor, error) {
+	r := &redactor{
+		keys: make(map[string]struct{}, len(config.Keys)),
+		mask: config.Mask,
+	}
+	if r.mask == "" {
+		r.mask = DefaultRedactionMask
+	}
+	for _, key := range config.Keys {
+		r.keys[strings.ToLower(key)] = struct{}{}
+	}
+
+	var errs []error
+	for _, pattern := range config.KeyPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid redaction key pattern %q: %v", pattern, err))
+			continue
+		}
+		r.patterns = append(r.patterns, strings.ToLower(pattern))
+	}
+	for _, pattern := range config.ValuePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid redaction value pattern %q: %v", pattern, err))
+			continue
+		}
+		r.values = append(r.values, re)
+	}
+	return r, errors.Join(errs...)
+}
+
+// matchKey reports whether the value of key, found at the dotted path within a field, is masked.
+func (r *redactor) matchKey(key, keyPath string) bool {
+	key = strings.ToLower(key)
+	if _, ok := r.keys[key]; ok {
+		return true
+	}
+
+	keyPath = strings.ToLower(keyPath)
+	for _, pattern := range r.patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, keyPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactString masks the matches of the value patterns in s.
+func (r *redactor) redactString(s string) string {
+	for _, re := range r.values {
+		s = re.ReplaceAllLiteralString(s, r.mask)
+	}
+	return s
+}
+
+// redactFields returns the fields with the sensitive values masked. The fields are copied
+// only when one of them changes.
+func (r *redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+	for i, field := range fields {
+		if f, ok := r.redactField(field); ok {
+			if redacted == nil {
+				redacted = append([]zapcore.Field(nil), fields...)
+			}
+			redacted[i] = f
+		}
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+// redactField returns the redacted field and true when field holds a sensitive value.
+func (r *redactor) redactField(field zapcore.Field) (zapcore.Field, bool) {
+	switch field.Type {
+	case zapcore.NamespaceType, zapcore.SkipType:
+		return field, false
+	case zapcore.InlineMarshalerType:
+		return r.redactInline(field)
+	}
+	if r.matchKey(field.Key, field.Key) {
+		return zap.String(field.Key, r.mask), true
+	}
+
+	switch field.Type {
+	case zapcore.StringType:
+		if s := r.redactString(field.String); s != field.String {
+			return zap.String(field.Key, s), true
+		}
+		return field, false
+	case zapcore.ByteStringType:
+		b := field.Interface.([]byte)
+		if s := r.redactString(string(b)); s != string(b) {
+			return zap.ByteString(field.Key, []byte(s)), true
+		}
+		return field, false
+	case zapcore.BinaryType:
+		// Binary values such as bodies are matched in their raw form, not in their base64 encoding
+		b := field.Interface.([]byte)
+		if s := r.redactString(string(b)); s != string(b) {
+			return zap.Binary(field.Key, []byte(s)), true
+		}
+		return field, false
+	case zapcore.ReflectType:
+		// Reflected values are redacted in their JSON form, which also covers structs
+		data, err := json.Marshal(field.Interface)
+		if err != nil {
+			return field, false
+		}
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return field, false
+		}
+		if redacted, changed := r.redactValue(value, field.Key); changed {
+			return zap.Any(field.Key, redacted), true
+		}
+		return field, false
+	case zapcore.ErrorType, zapcore.StringerType, zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		field.AddTo(enc)
+		value, ok := enc.Fields[field.Key]
+		if !ok {
+			return field, false
+		}
+		if redacted, changed := r.redactValue(value, field.Key); changed {
+			return zap.Any(field.Key, redacted), true
+		}
+		return field, false
+	default:
+		return field, false
+	}
+}
+
+// redactInline redacts the fields added by an inlined object, which have no key of their own.
+func (r *redactor) redactInline(field zapcore.Field) (zapcore.Field, bool) {
+	enc := zapcore.NewMapObjectEncoder()
+	field.AddTo(enc)
+	redacted, changed := r.redactValue(enc.Fields, "")
+	if !changed {
+		return field, false
+	}
+	fields := redacted.(map[string]interface{})
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return zap.Inline(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		for _, key := range keys {
+			zap.Any(key, fields[key]).AddTo(enc)
+		}
+		return nil
+	})), true
+}
+
+// redactValue redacts the strings and the values of the sensitive keys of a value decoded from
+// JSON or encoded by zapcore.MapObjectEncoder. It reports whether anything was masked.
+func (r *redactor) redactValue(value interface{}, keyPath string) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		s := r.redactString(v)
+		return s, s != v
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		changed := false
+		for key, child := range v {
+			childPath := key
+			if keyPath != "" {
+				childPath = keyPath + "." + key
+			}
+			if r.matchKey(key, childPath) {
+				redacted[key] = r.mask
+				changed = true
+				continue
+			}
+			var childChanged bool
+			redacted[key], childChanged = r.redactValue(child, childPath)
+			changed = changed || childChanged
+		}
+		return redacted, changed
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		changed := false
+		for i, child := range v {
+			var childChanged bool
+			redacted[i], childChanged = r.redactValue(child, keyPath)
+			changed = changed || childChanged
+		}
+		return redacted, changed
+	default:
+		return value, false
+	}
+}
+
+// redactCore redacts the messages and fields of the entries once for all the cores it wraps.
+type redactCore struct {
+	zapcore.Core
+	redactor *redactor
+}
+
+func newRedactCore(core zapcore.Core, redactor *redactor) zapcore.Core {
+	return &redactCore{Core: core, redactor: redactor}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return newRedactCore(c.Core.With(c.redactor.redactFields(fields)), c.redactor)
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	checked := c.Core.Check(ent, nil)
+	if checked == nil {
+		return ce
+	}
+	checked.ErrorOutput = stderrOutput
+	return ce.AddCore(ent, redactedCore{checked: checked, redactor: c.redactor})
+}
+
+// redactedCore redacts an entry, then writes it to the cores it was checked against.
+type redactedCore struct {
+	checked  *zapcore.CheckedEntry
+	redactor *redactor
+}
+
+func (c redactedCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c redactedCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c redactedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c redactedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.redactor.redactString(ent.Message)
+	return checkedCore{checked: c.checked}.Write(ent, c.redactor.redactFields(fields))
+}
+
+func (c redactedCore) Sync() error {
+	return nil
+}
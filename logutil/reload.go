@@ -0,0 +1,448 @@
+package logutil
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultWatchInterval = 2 * time.Second
+
+// drainTimeout bounds the wait for the entries being written to a closing core. Entries checked
+// and never written, e.g. when a caller drops the result of Check, are given up on after it.
+var drainTimeout = 5 * time.Second
+
+// stderrOutput reports the write errors of the cores, like zap's default ErrorOutput.
+var stderrOutput = zapcore.Lock(os.Stderr)
+
+// builtCore is the core writing to the outputs of a config, along with the resources it owns.
+type builtCore struct {
+	core    zapcore.Core
+	file    *rotateFile // nil when the file output is disabled
+	closers []io.Closer
+	// keepFile is set when the file was handed over to the core replacing this one
+	keepFile bool
+	redactor *redactor // nil when redaction is disabled
+
+	// inflight counts the entries checked against the core and not written yet
+	inflight atomic.Int64
+	retired  atomic.Bool
+}
+
+// acquire registers an entry about to be written to the core. It fails once the core is closing.
+func (b *builtCore) acquire() bool {
+	b.inflight.Add(1)
+	if b.retired.Load() {
+		b.inflight.Add(-1)
+		return false
+	}
+	return true
+}
+
+// release marks an entry registered by acquire as written.
+func (b *builtCore) release() {
+	b.inflight.Add(-1)
+}
+
+// close waits until the entries being written to the core are done, at most drainTimeout, then
+// flushes the core and closes its resources in order. The core must not be reachable for new
+// entries anymore.
+func (b *builtCore) close() error {
+	b.retired.Store(true)
+	// Closing is rare, polling keeps acquire and release down to atomic operations
+	deadline := time.Now().Add(drainTimeout)
+	for b.inflight.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	_ = b.core.Sync()
+	var errs []error
+	for _, closer := range b.closers {
+		errs = append(errs, closer.Close())
+	}
+	if b.file != nil && !b.keepFile {
+		errs = append(errs, b.file.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// rootCore holds the core shared by a logger and its children.
+type rootCore struct {
+	mu      sync.Mutex // serializes ApplyConfig and close
+	config  LoggerConfig
+	closed  bool
+	current atomic.Pointer[builtCore]
+}
+
+// close replaces the current core by a core discarding entries, then flushes and closes the
+// outputs, including the ones of the custom sinks.
+func (r *rootCore) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	built := r.current.Swap(&builtCore{core: zapcore.NewNopCore()})
+	errs := []error{built.close()}
+	for _, sink := range r.config.Sinks {
+		if sink.closer != nil {
+			errs = append(errs, sink.closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sync flushes the current core, which stays open until the flush is done.
+func (r *rootCore) sync() error {
+	for {
+		built := r.current.Load()
+		if built.acquire() {
+			defer built.release()
+			return built.core.Sync()
+		}
+	}
+}
+
+// redact masks the sensitive values of msg like the current core does.
+func (r *rootCore) redact(msg string) string {
+	redactor := r.current.Load().redactor
+	if redactor == nil {
+		return msg
+	}
+	return redactor.redactString(msg)
+}
+
+// swapCore writes to the current core of its root, adding the fields of the child logger it belongs to.
+type swapCore struct {
+	root   *rootCore
+	fields []zapcore.Field
+	cache  atomic.Pointer[swapCache]
+}
+
+// swapCache is the core of a child logger built for a given root core.
+type swapCache struct {
+	built *builtCore
+	core  zapcore.Core
+}
+
+// coreOf returns the core of the child logger built for the root core built.
+func (c *swapCore) coreOf(built *builtCore) zapcore.Core {
+	if len(c.fields) == 0 {
+		return built.core
+	}
+	if cache := c.cache.Load(); cache != nil && cache.built == built {
+		return cache.core
+	}
+
+	core := built.core.With(c.fields)
+	c.cache.Store(&swapCache{built: built, core: core})
+	return core
+}
+
+// acquire returns the current root core, registered for an entry, and the core of the child logger.
+func (c *swapCore) acquire() (*builtCore, zapcore.Core) {
+	for {
+		built := c.root.current.Load()
+		if built.acquire() {
+			return built, c.coreOf(built)
+		}
+		// The core is closing after being swapped, the next load returns its replacement
+	}
+}
+
+func (c *swapCore) Enabled(level zapcore.Level) bool {
+	return c.coreOf(c.root.current.Load()).Enabled(level)
+}
+
+func (c *swapCore) With(fields []zapcore.Field) zapcore.Core {
+	return &swapCore{
+		root:   c.root,
+		fields: append(append([]zapcore.Field(nil), c.fields...), fields...),
+	}
+}
+
+// Check checks the entry against the current core, which stays open until the entry is written.
+func (c *swapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	built, core := c.acquire()
+	checked := core.Check(ent, nil)
+	if checked == nil {
+		built.release()
+		return ce
+	}
+	checked.ErrorOutput = stderrOutput
+	pinned := pinnedPool.Get().(*pinnedCore)
+	pinned.built, pinned.checked = built, checked
+	return ce.AddCore(ent, pinned)
+}
+
+func (c *swapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	built, core := c.acquire()
+	defer built.release()
+	return core.Write(ent, fields)
+}
+
+func (c *swapCore) Sync() error {
+	return c.root.sync()
+}
+
+// pinnedCore writes an entry checked against a root core, then releases the root core.
+type pinnedCore struct {
+	built   *builtCore
+	checked *zapcore.CheckedEntry
+}
+
+var pinnedPool = sync.Pool{New: func() interface{} { return &pinnedCore{} }}
+
+func (c *pinnedCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *pinnedCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c *pinnedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *pinnedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	defer func() {
+		c.built.release()
+		c.built, c.checked = nil, nil
+		pinnedPool.Put(c)
+	}()
+	// The caller and stack are added to the entry after Check
+	c.checked.Entry = ent
+	c.checked.Write(fields...)
+	return nil
+}
+
+func (c *pinnedCore) Sync() error {
+	return nil
+}
+
+// ApplyConfig applies config to the logger and all loggers derived from it. The outputs are rebuilt
+// and swapped atomically when they change, the replaced ones are flushed and closed once the entries
+// being written to them are done. The log file stays open when its own options don't change.
+// The Name, Sinks, CallerEnabled, StacktraceEnabled and StacktraceLevel of the logger can't change
+// and are kept, their changes are logged as ignored. Levels are only set when they differ from the previously applied config, so levels
+// changed at runtime survive unrelated changes. The applied changes are logged.
+func (l *logger) ApplyConfig(config LoggerConfig) error {
+	root := l.root
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	if root.closed {
+		return errors.New("logger is closed")
+	}
+
+	previous := root.config
+	requested := config
+	config.Name = previous.Name
+	config.Sinks = previous.Sinks
+	config.CallerEnabled = previous.CallerEnabled
+	config.StacktraceEnabled = previous.StacktraceEnabled
+	config.StacktraceLevel = previous.StacktraceLevel
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	// Report the changes which can't be applied, so that editing them doesn't go unnoticed
+	if ignored := configChanges(config, requested); len(ignored) > 0 {
+		l.SugaredLogger.Warnw("logger config changes ignored", "ignored", ignored)
+	}
+
+	changes := configChanges(previous, config)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// Levels are atomic, other changes need new outputs
+	rebuild := false
+	for _, change := range changes {
+		if !strings.HasPrefix(change, "console_level:") && !strings.HasPrefix(change, "file_level:") {
+			rebuild = true
+		}
+	}
+	if rebuild {
+		// The file is handed over when its options don't change, so that a single lumberjack
+		// logger writes and rotates it
+		current := root.current.Load()
+		var file *rotateFile
+		if sameFileOutput(previous, config) {
+			file = current.file
+		}
+		built, err := l.build(config, file)
+		if err != nil {
+			built.keepFile = file != nil
+			_ = built.close()
+			return err
+		}
+		current.keepFile = file != nil
+		_ = root.current.Swap(built).close()
+	}
+
+	if config.ConsoleLevel != previous.ConsoleLevel {
+		l.consoleAtomLvl.SetLevel(config.ConsoleLevel.zapLevel())
+	}
+	if config.FileLevel != previous.FileLevel {
+		l.fileAtomLvl.SetLevel(config.FileLevel.zapLevel())
+	}
+	root.config = config
+
+	l.SugaredLogger.Infow("logger config changed", "changes", changes)
+	return nil
+}
+
+// sameFileOutput reports whether the file output of both configs writes the same file the same way.
+func sameFileOutput(a, b LoggerConfig) bool {
+	return a.FileEnabled && b.FileEnabled &&
+		a.LogDirectory == b.LogDirectory && a.Filename == b.Filename &&
+		a.MaxSize == b.MaxSize && a.MaxAge == b.MaxAge && a.MaxBackup == b.MaxBackup &&
+		a.Rotation == b.Rotation && a.Compress == b.Compress && a.LocalTime == b.LocalTime &&
+		a.MaxTotalSize == b.MaxTotalSize
+}
+
+// configChanges describes the fields differing between old and new as "name: old -> new",
+// naming the fields by their json tags.
+func configChanges(old, new LoggerConfig) []string {
+	var changes []string
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < oldValue.NumField(); i++ {
+		name, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		before, after := configValue(oldValue.Field(i)), configValue(newValue.Field(i))
+		if before != after {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", name, before, after))
+		}
+	}
+	return changes
+}
+
+func configValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "<nil>"
+		}
+		return fmt.Sprintf("%+v", v.Elem().Interface())
+	}
+	// Use the config file form of the values when they have one
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// ConfigWatcher applies the config file it watches to a Logger whenever the file changes.
+type ConfigWatcher struct {
+	logger  Logger
+	applier ConfigApplier
+	path    string
+
+	modTime time.Time
+	size    int64
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// WatchConfig polls the config file at path every interval, defaulting to two seconds, and applies
+// it to l with ApplyConfig when it changes. The file is loaded by LoadLoggerConfig. Invalid configs
+// are logged and ignored, the logger keeps its current config. It fails when l is not a ConfigApplier.
+func WatchConfig(l Logger, path string, interval time.Duration) (*ConfigWatcher, error) {
+	applier, ok := l.(ConfigApplier)
+	if !ok {
+		return nil, fmt.Errorf("logger does not support config reload")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not watch logger config. err: %v", err)
+	}
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	w := &ConfigWatcher{
+		logger:  l,
+		applier: applier,
+		path:    path,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run(interval)
+	return w, nil
+}
+
+// Reload loads the config file and applies it to the logger.
+func (w *ConfigWatcher) Reload() error {
+	config, err := LoadLoggerConfig(w.path)
+	if err != nil {
+		return err
+	}
+	return w.applier.ApplyConfig(config)
+}
+
+// Close stops watching the config file.
+func (w *ConfigWatcher) Close() error {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+	return nil
+}
+
+func (w *ConfigWatcher) run(interval time.Duration) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !w.changed() {
+				continue
+			}
+			if err := w.Reload(); err != nil {
+				w.logger.Errorw("could not reload logger config", "path", w.path, "err", err)
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// changed reports whether the file was modified since the last call.
+func (w *ConfigWatcher) changed() bool {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		// The file may be in the middle of being replaced
+		return false
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false
+	}
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+	return true
+}
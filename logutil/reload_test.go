@@ -0,0 +1,212 @@
+package logutil
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func readEntries(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	return decodeLines(t, bytes.NewBuffer(data))
+}
+
+func TestApplyConfig(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	config := LoggerConfig{
+		FileEnabled:  true,
+		FileLevel:    InfoLevel,
+		FileEncoding: JSONEncoding,
+		LogDirectory: first,
+		Filename:     "app.log",
+	}
+	l, err := NewLoggerE(config)
+	require.Nil(t, err)
+
+	child := l.With("k", "v").Named("child")
+	child.Info("before")
+	child.Debug("hidden")
+
+	config.LogDirectory = second
+	config.FileLevel = DebugLevel
+	config.Name = "ignored"
+	require.Nil(t, l.(ConfigApplier).ApplyConfig(config))
+	child.Debug("after")
+	require.Nil(t, l.Sync())
+
+	entries := readEntries(t, filepath.Join(first, "app.log"))
+	require.Equal(t, "before", entries[0]["msg"])
+
+	entries = readEntries(t, filepath.Join(second, "app.log"))
+	require.Len(t, entries, 2)
+	require.Equal(t, "logger config changed", entries[0]["msg"])
+	require.ElementsMatch(t, []interface{}{
+		"file_level: info -> debug",
+		"log_directory: " + first + " -> " + second,
+	}, entries[0]["changes"])
+	require.Equal(t, "after", entries[1]["msg"])
+	require.Equal(t, "child", entries[1]["logger"])
+	require.Equal(t, "v", entries[1]["k"])
+
+	// Changes which can't be applied are reported before the swap
+	entries = readEntries(t, filepath.Join(first, "app.log"))
+	require.Len(t, entries, 2)
+	require.Equal(t, "logger config changes ignored", entries[1]["msg"])
+	require.Equal(t, []interface{}{"name:  -> ignored"}, entries[1]["ignored"])
+
+	// Levels changed at runtime survive unrelated changes
+	l.SetFileLevel(WarnLevel)
+	config.FileEncoding = LogfmtEncoding
+	require.Nil(t, l.(ConfigApplier).ApplyConfig(config))
+	require.Equal(t, WarnLevel, l.(LevelReporter).FileLevel())
+
+	// Invalid configs are rejected
+	config.MaxSize = -1
+	require.NotNil(t, l.(ConfigApplier).ApplyConfig(config))
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logger.yaml")
+	require.Nil(t, os.WriteFile(path, []byte("console_level: info\n"), 0o644))
+
+	config, err := LoadLoggerConfig(path)
+	require.Nil(t, err)
+	l, err := NewLoggerE(config)
+	require.Nil(t, err)
+
+	w, err := WatchConfig(l, path, 10*time.Millisecond)
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.Nil(t, os.WriteFile(path, []byte("console_level: debug\n"), 0o644))
+	require.Eventually(t, func() bool {
+		return l.(LevelReporter).ConsoleLevel() == DebugLevel
+	}, time.Second, 10*time.Millisecond)
+
+	require.Nil(t, os.WriteFile(path, []byte("console_level: verbose\n"), 0o644))
+	require.NotNil(t, w.Reload())
+	require.Equal(t, DebugLevel, l.(LevelReporter).ConsoleLevel())
+
+	_, err = WatchConfig(l, filepath.Join(dir, "missing.yaml"), 0)
+	require.NotNil(t, err)
+	_, err = WatchConfig(mapLogger{Logger: l}, path, 0)
+	require.ErrorContains(t, err, "does not support config reload")
+}
+
+func TestApplyConfigWaitsForInflightEntries(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	close(w.release)
+	config := LoggerConfig{
+		Sinks: []Sink{NewWriterSink("buf", w, DebugLevel, JSONEncoding)},
+		Async: &AsyncConfig{QueueSize: 10, Overflow: OverflowDropNewest},
+	}
+	l, err := NewLoggerE(config)
+	require.Nil(t, err)
+
+	// The entry checked against the replaced core is written before the core is closed
+	ce := l.(*logger).unsugared.Check(zapcore.InfoLevel, "late")
+	require.NotNil(t, ce)
+	applied := make(chan error, 1)
+	go func() {
+		config.Async = &AsyncConfig{QueueSize: 20, Overflow: OverflowDropNewest}
+		applied <- l.(ConfigApplier).ApplyConfig(config)
+	}()
+	select {
+	case <-applied:
+		t.Fatal("ApplyConfig returned before the checked entry was written")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ce.Write()
+	require.Nil(t, <-applied)
+	require.Contains(t, w.String(), `"msg":"late"`)
+}
+
+func TestApplyConfigDroppedCheck(t *testing.T) {
+	defer func(timeout time.Duration) { drainTimeout = timeout }(drainTimeout)
+	drainTimeout = 50 * time.Millisecond
+
+	var buf bytes.Buffer
+	config := LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)}}
+	l, err := NewLoggerE(config)
+	require.Nil(t, err)
+
+	// A checked entry which is never written doesn't block the reload forever
+	require.NotNil(t, l.(*logger).unsugared.Check(zapcore.InfoLevel, "dropped"))
+	config.Async = &AsyncConfig{QueueSize: 20}
+	applied := make(chan error, 1)
+	go func() { applied <- l.(ConfigApplier).ApplyConfig(config) }()
+	select {
+	case err := <-applied:
+		require.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ApplyConfig waits for the dropped entry")
+	}
+}
+
+func TestApplyConfigConcurrentLogging(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	close(w.release)
+	config := LoggerConfig{
+		Sinks: []Sink{NewWriterSink("buf", w, DebugLevel, JSONEncoding)},
+		Async: &AsyncConfig{QueueSize: 10000, Overflow: OverflowDropNewest},
+	}
+	l, err := NewLoggerE(config)
+	require.Nil(t, err)
+
+	const n = 2000
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		child := l.Named("worker")
+		for i := 0; i < n; i++ {
+			child.Infow("entry", "i", i)
+			if i%100 == 0 {
+				_ = child.Sync()
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		config.Async = &AsyncConfig{QueueSize: 10000 + i%2, Overflow: OverflowDropNewest}
+		require.Nil(t, l.(ConfigApplier).ApplyConfig(config))
+	}
+	<-done
+	require.Nil(t, l.(io.Closer).Close())
+
+	count := 0
+	for _, entry := range decodeLines(t, bytes.NewBufferString(w.String())) {
+		if entry["msg"] == "entry" {
+			count++
+		}
+	}
+	require.Equal(t, n, count)
+}
+
+func TestApplyConfigKeepsFile(t *testing.T) {
+	config := LoggerConfig{
+		FileEnabled:  true,
+		FileEncoding: JSONEncoding,
+		LogDirectory: t.TempDir(),
+		Filename:     "app.log",
+	}
+	l, err := NewLoggerE(config)
+	require.Nil(t, err)
+	root := l.(*logger).root
+	file := root.current.Load().file
+
+	config.FileEncoding = LogfmtEncoding
+	require.Nil(t, l.(ConfigApplier).ApplyConfig(config))
+	require.Same(t, file, root.current.Load().file)
+
+	config.MaxSize = 10
+	require.Nil(t, l.(ConfigApplier).ApplyConfig(config))
+	require.NotSame(t, file, root.current.Load().file)
+}
@@ -0,0 +1,270 @@
+package logutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	megabyte = 1024 * 1024
+	// backupTimeFormat is the timestamp lumberjack inserts in the names of rotated files
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	// pruneInterval is how often the total size of the log files is checked, in addition to the
+	// checks following the rotations
+	pruneInterval = time.Minute
+)
+
+// RotationInterval is the time based rotation of the log file.
+type RotationInterval uint8
+
+const (
+	// RotateNever only rotates the log file when it reaches its max size.
+	RotateNever RotationInterval = iota
+	// RotateHourly rotates the log file at the start of every hour.
+	RotateHourly
+	// RotateDaily rotates the log file at midnight.
+	RotateDaily
+)
+
+func (r RotationInterval) String() string {
+	switch r {
+	case RotateNever:
+		return "never"
+	case RotateHourly:
+		return "hourly"
+	case RotateDaily:
+		return "daily"
+	default:
+		return fmt.Sprintf("RotationInterval(%d)", r)
+	}
+}
+
+// MarshalText marshals the RotationInterval to its name.
+func (r RotationInterval) MarshalText() ([]byte, error) {
+	if r > RotateDaily {
+		return nil, fmt.Errorf("unknown rotation interval: %d", r)
+	}
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText unmarshals a case-insensitive interval name such as "daily" to a RotationInterval.
+// An empty text is RotateNever.
+func (r *RotationInterval) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*r = RotateNever
+		return nil
+	}
+	for interval := RotateNever; interval <= RotateDaily; interval++ {
+		if strings.EqualFold(string(text), interval.String()) {
+			*r = interval
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown rotation interval: %q", text)
+}
+
+// next returns the first boundary of the interval after t, in the location of t.
+func (r RotationInterval) next(t time.Time) time.Time {
+	y, m, d := t.Date()
+	if r == RotateHourly {
+		return time.Date(y, m, d, t.Hour()+1, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+}
+
+// rotateFile is the log file. lumberjack rotates it when it reaches its max size, rotateFile also
+// rotates it at the boundaries of the rotation interval and removes the oldest backups when the
+// log files exceed their total size cap.
+type rotateFile struct {
+	*lumberjack.Logger
+	config LoggerConfig
+
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func newRotateFileLogger(config LoggerConfig, file *lumberjack.Logger) *rotateFile {
+	f := &rotateFile{Logger: file, config: config}
+	if config.Rotation != RotateNever || config.MaxTotalSize > 0 {
+		f.done = make(chan struct{})
+		f.stopped = make(chan struct{})
+		go f.run()
+	}
+	return f
+}
+
+func (f *rotateFile) run() {
+	defer close(f.stopped)
+
+	var rotate <-chan time.Time
+	var timer *time.Timer
+	var boundary time.Time
+	if f.config.Rotation != RotateNever {
+		boundary = f.config.Rotation.next(f.now())
+		timer = time.NewTimer(time.Until(boundary))
+		defer timer.Stop()
+		rotate = timer.C
+	}
+	var prune <-chan time.Time
+	if f.config.MaxTotalSize > 0 {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		prune = ticker.C
+	}
+
+	for {
+		select {
+		case <-rotate:
+			_ = f.rotateAtBoundary()
+			// The timer may fire late, e.g. after a suspend, but never rotates twice per boundary
+			now := f.now()
+			if now.Before(boundary) {
+				now = boundary
+			}
+			boundary = f.config.Rotation.next(now)
+			timer.Reset(time.Until(boundary))
+		case <-prune:
+			_ = f.prune()
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// rotateAtBoundary rotates the log file unless it is empty, idle loggers don't leave empty backups.
+func (f *rotateFile) rotateAtBoundary() error {
+	info, err := os.Stat(f.Filename)
+	if err != nil || info.Size() == 0 {
+		return nil
+	}
+	return f.Rotate()
+}
+
+// now returns the current time in the location of the backup names.
+func (f *rotateFile) now() time.Time {
+	if f.config.LocalTime {
+		return time.Now()
+	}
+	return time.Now().UTC()
+}
+
+// Rotate rotates the log file, then removes the backups exceeding the total size cap.
+func (f *rotateFile) Rotate() error {
+	if err := f.Logger.Rotate(); err != nil {
+		return err
+	}
+	return f.prune()
+}
+
+// prune removes the oldest backups of the log file while the files of its directory exceed
+// MaxTotalSize. The current log file, the files of other loggers and the backups lumberjack is
+// compressing are never removed.
+func (f *rotateFile) prune() error {
+	if f.config.MaxTotalSize <= 0 {
+		return nil
+	}
+	dir := filepath.Dir(f.Filename)
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("could not read log directory. err: %v", err)
+	}
+	sizes := make(map[string]int64, len(dirEntries))
+	var total int64
+	for _, dirEntry := range dirEntries {
+		if info, err := dirEntry.Info(); err == nil && info.Mode().IsRegular() {
+			sizes[dirEntry.Name()] = info.Size()
+			total += info.Size()
+		}
+	}
+
+	// lumberjack writes the compressed copy of a backup next to it before removing the backup,
+	// both are counted once and kept until the compression is done
+	compressing := func(name string) bool {
+		plain := strings.TrimSuffix(name, ".gz")
+		_, hasPlain := sizes[plain]
+		_, hasCompressed := sizes[plain+".gz"]
+		return hasPlain && hasCompressed
+	}
+	backups, err := logBackups(dir, filepath.Base(f.Filename))
+	if err != nil {
+		return err
+	}
+	for _, path := range backups {
+		if name := filepath.Base(path); strings.HasSuffix(name, ".gz") && compressing(name) {
+			total -= sizes[name]
+		}
+	}
+
+	for _, path := range backups {
+		if total <= int64(f.config.MaxTotalSize)*megabyte {
+			break
+		}
+		name := filepath.Base(path)
+		if compressing(name) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove log backup. err: %v", err)
+		}
+		total -= sizes[name]
+	}
+	return nil
+}
+
+// logBackups returns the rotated backups of the log file filename in dir, oldest first.
+// Compressed backups are included.
+func logBackups(dir, filename string) ([]string, error) {
+	ext := filepath.Ext(filename)
+	prefix := strings.TrimSuffix(filename, ext) + "-"
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read log directory. err: %v", err)
+	}
+
+	type backup struct {
+		path string
+		time time.Time
+	}
+	var backups []backup
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		t, err := time.Parse(backupTimeFormat, strings.TrimPrefix(stamp, prefix))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, name), time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.Before(backups[j].time)
+	})
+
+	paths := make([]string, 0, len(backups))
+	for _, b := range backups {
+		paths = append(paths, b.path)
+	}
+	return paths, nil
+}
+
+// Close stops the rotation and closes the log file.
+func (f *rotateFile) Close() error {
+	if f.done != nil {
+		f.closeOnce.Do(func() {
+			close(f.done)
+		})
+		<-f.stopped
+	}
+	return f.Logger.Close()
+}
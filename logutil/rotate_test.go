@@ -0,0 +1,132 @@
+package logutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+func TestRotationInterval(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	now := time.Date(2024, 5, 6, 23, 30, 15, 0, loc)
+	require.Equal(t, time.Date(2024, 5, 7, 0, 0, 0, 0, loc), RotateHourly.next(now))
+	require.Equal(t, time.Date(2024, 5, 7, 0, 0, 0, 0, loc), RotateDaily.next(now))
+	require.Equal(t, time.Date(2024, 5, 6, 12, 0, 0, 0, loc), RotateHourly.next(time.Date(2024, 5, 6, 11, 0, 0, 0, loc)))
+
+	config, err := LoggerConfigFromYAML([]byte("rotation: Daily\ncompress: true\nlocal_time: true\nmax_total_size: 100\n"))
+	require.Nil(t, err)
+	require.Equal(t, RotateDaily, config.Rotation)
+	require.True(t, config.Compress)
+	require.True(t, config.LocalTime)
+	require.Equal(t, 100, config.MaxTotalSize)
+
+	_, err = LoggerConfigFromYAML([]byte("rotation: weekly\n"))
+	require.NotNil(t, err)
+	require.NotNil(t, LoggerConfig{Rotation: 3}.Validate())
+	require.NotNil(t, LoggerConfig{MaxTotalSize: -1}.Validate())
+}
+
+func TestRotateAtBoundary(t *testing.T) {
+	dir := t.TempDir()
+	config := LoggerConfig{LogDirectory: dir, Filename: "app.log"}
+	f := newRotateFileLogger(config, &lumberjack.Logger{Filename: filepath.Join(dir, "app.log")})
+	defer f.Close()
+
+	// Empty files are not rotated
+	require.Nil(t, f.rotateAtBoundary())
+	backups, err := logBackups(dir, "app.log")
+	require.Nil(t, err)
+	require.Empty(t, backups)
+
+	_, err = f.Write([]byte("entry\n"))
+	require.Nil(t, err)
+	require.Nil(t, f.rotateAtBoundary())
+	backups, err = logBackups(dir, "app.log")
+	require.Nil(t, err)
+	require.Len(t, backups, 1)
+}
+
+func TestPruneBackups(t *testing.T) {
+	dir := t.TempDir()
+	chunk := bytes.Repeat([]byte("x"), 400*1024)
+	names := []string{
+		"app-2024-05-06T07-08-09.000.log.gz",
+		"app-2024-05-07T07-08-09.000.log",
+		"app-2024-05-08T07-08-09.000.log",
+		"app.log",
+		"other.log",
+	}
+	for _, name := range names {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), chunk, 0o600))
+	}
+	// The second backup is being compressed
+	require.Nil(t, os.WriteFile(filepath.Join(dir, names[1]+".gz"), chunk[:100*1024], 0o600))
+
+	config := LoggerConfig{LogDirectory: dir, Filename: "app.log", MaxTotalSize: 1}
+	f := newRotateFileLogger(config, &lumberjack.Logger{Filename: filepath.Join(dir, "app.log")})
+	defer f.Close()
+	require.Nil(t, f.prune())
+
+	// 2 MB of files, the backup being compressed counted once, are pruned down to 1.2 MB: the
+	// backup being compressed, the current file and the files of other loggers are kept
+	backups, err := logBackups(dir, "app.log")
+	require.Nil(t, err)
+	require.Equal(t, []string{filepath.Join(dir, names[1]), filepath.Join(dir, names[1]+".gz")}, backups)
+	require.FileExists(t, filepath.Join(dir, "app.log"))
+	require.FileExists(t, filepath.Join(dir, "other.log"))
+
+	// Once compressed, the backup fits under the cap
+	require.Nil(t, os.Remove(filepath.Join(dir, names[1])))
+	require.Nil(t, f.prune())
+	backups, err = logBackups(dir, "app.log")
+	require.Nil(t, err)
+	require.Equal(t, []string{filepath.Join(dir, names[1]+".gz")}, backups)
+
+	// The current file is kept even when it exceeds the cap on its own
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "app.log"), bytes.Repeat(chunk, 3), 0o600))
+	require.Nil(t, f.prune())
+	backups, err = logBackups(dir, "app.log")
+	require.Nil(t, err)
+	require.Empty(t, backups)
+	require.FileExists(t, filepath.Join(dir, "app.log"))
+}
+
+func TestCompressedRotation(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLoggerE(LoggerConfig{
+		FileEnabled:  true,
+		FileEncoding: JSONEncoding,
+		LogDirectory: dir,
+		Filename:     "app.log",
+		Compress:     true,
+		LocalTime:    true,
+	})
+	require.Nil(t, err)
+
+	l.Info("compressed")
+	require.Nil(t, l.(Rotator).Rotate())
+
+	// lumberjack compresses the backups in the background
+	var backups []string
+	require.Eventually(t, func() bool {
+		backups, err = logBackups(dir, "app.log")
+		return err == nil && len(backups) == 1 && strings.HasSuffix(backups[0], ".log.gz")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	file, err := os.Open(backups[0])
+	require.Nil(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.Nil(t, err)
+	content, err := io.ReadAll(gz)
+	require.Nil(t, err)
+	require.Contains(t, string(content), `"msg":"compressed"`)
+}
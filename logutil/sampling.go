@@ -0,0 +1,290 @@
+package logutil
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultSamplingTick       = time.Second
+	defaultRateLimitKeys      = 1024
+	defaultDropReportInterval = 10 * time.Second
+	droppedEntriesMessage     = "dropped log entries"
+)
+
+// SamplingConfig configures the sampling of entries with the same level and message.
+type SamplingConfig struct {
+	// Tick is the sampling interval, defaults to one second
+	Tick time.Duration `json:"tick" yaml:"tick" env:"TICK"`
+	// Initial is the number of entries with the same level and message logged per Tick
+	Initial int `json:"initial" yaml:"initial" env:"INITIAL"`
+	// Thereafter every Thereafter-th of the remaining entries is logged within the Tick. Zero drops them all.
+	Thereafter int `json:"thereafter" yaml:"thereafter" env:"THEREAFTER"`
+}
+
+// RateLimitConfig configures the token bucket rate limiting of entries. Each logger name, level
+// and message has its own bucket.
+type RateLimitConfig struct {
+	// Rate is the number of entries per second refilled to each bucket
+	Rate float64 `json:"rate" yaml:"rate" env:"RATE"`
+	// Burst is the capacity of each bucket, defaults to one
+	Burst int `json:"burst" yaml:"burst" env:"BURST"`
+	// MaxKeys bounds the number of buckets kept in memory, defaults to 1024. All buckets are
+	// reset when the bound is exceeded.
+	MaxKeys int `json:"max_keys" yaml:"max_keys" env:"MAX_KEYS"`
+}
+
+// dropCounter counts the entries dropped by sampling and rate limiting.
+type dropCounter struct {
+	dropped    atomic.Uint64
+	lastReport atomic.Int64
+	interval   time.Duration
+	name       string
+}
+
+func newDropCounter(interval time.Duration, name string) *dropCounter {
+	if interval <= 0 {
+		interval = defaultDropReportInterval
+	}
+	c := &dropCounter{interval: interval, name: name}
+	c.lastReport.Store(time.Now().UnixNano())
+	return c
+}
+
+func (c *dropCounter) add() {
+	c.dropped.Add(1)
+}
+
+// take returns the number of entries dropped since the last report, when a report is due.
+func (c *dropCounter) take(now time.Time, force bool) uint64 {
+	if c.dropped.Load() == 0 {
+		return 0
+	}
+
+	last := c.lastReport.Load()
+	if !force && now.UnixNano()-last < int64(c.interval) {
+		return 0
+	}
+	if !c.lastReport.CompareAndSwap(last, now.UnixNano()) {
+		return 0
+	}
+	return c.dropped.Swap(0)
+}
+
+// dropReportCore logs the number of dropped entries as its own entry, at most once per interval.
+// Reports are written to base, bypassing sampling and rate limiting.
+type dropReportCore struct {
+	zapcore.Core
+	base    zapcore.Core
+	counter *dropCounter
+}
+
+func newDropReportCore(core, base zapcore.Core, counter *dropCounter) *dropReportCore {
+	return &dropReportCore{Core: core, base: base, counter: counter}
+}
+
+func (c *dropReportCore) With(fields []zapcore.Field) zapcore.Core {
+	return newDropReportCore(c.Core.With(fields), c.base, c.counter)
+}
+
+func (c *dropReportCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	c.report(ent.Time, false)
+	return c.Core.Check(ent, ce)
+}
+
+func (c *dropReportCore) Sync() error {
+	c.report(time.Now(), true)
+	return c.Core.Sync()
+}
+
+func (c *dropReportCore) report(now time.Time, force bool) {
+	dropped := c.counter.take(now, force)
+	if dropped == 0 {
+		return
+	}
+
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       now,
+		LoggerName: c.counter.name,
+		Message:    droppedEntriesMessage,
+	}
+	if ce := c.base.Check(ent, nil); ce != nil {
+		ce.Write(Uint64("dropped", dropped))
+	}
+}
+
+// dropReporter checks for dropped entries every interval, so that they are reported even when
+// nothing is logged after the drops.
+type dropReporter struct {
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func startDropReporter(core *dropReportCore) *dropReporter {
+	r := &dropReporter{done: make(chan struct{}), stopped: make(chan struct{})}
+	go func() {
+		defer close(r.stopped)
+
+		ticker := time.NewTicker(core.counter.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				core.report(now, false)
+			case <-r.done:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// Close stops the reporter.
+func (r *dropReporter) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+	<-r.stopped
+	return nil
+}
+
+// newSamplerCore returns core sampling entries as configured, counting the dropped ones.
+func newSamplerCore(core zapcore.Core, config SamplingConfig, counter *dropCounter) zapcore.Core {
+	tick := config.Tick
+	if tick <= 0 {
+		tick = defaultSamplingTick
+	}
+	return zapcore.NewSamplerWithOptions(core, tick, config.Initial, config.Thereafter,
+		zapcore.SamplerHook(func(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped > 0 {
+				counter.add()
+			}
+		}))
+}
+
+// rateLimiter is a set of token buckets keyed by strings.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	maxKeys int
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	burst := config.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	maxKeys := config.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = defaultRateLimitKeys
+	}
+	return &rateLimiter{
+		rate:    config.Rate,
+		burst:   float64(burst),
+		maxKeys: maxKeys,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from the bucket of key, reporting whether one was available.
+func (r *rateLimiter) allow(key string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, ok := r.buckets[key]
+	if !ok {
+		if len(r.buckets) >= r.maxKeys {
+			r.buckets = make(map[string]*tokenBucket)
+		}
+		bucket = &tokenBucket{tokens: r.burst, last: now}
+		r.buckets[key] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * r.rate
+	if bucket.tokens > r.burst {
+		bucket.tokens = r.burst
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// rateLimitCore drops the entries exceeding the rate of their logger name, level and message.
+type rateLimitCore struct {
+	zapcore.Core
+	limiter *rateLimiter
+	counter *dropCounter
+}
+
+func newRateLimitCore(core zapcore.Core, limiter *rateLimiter, counter *dropCounter) zapcore.Core {
+	return &rateLimitCore{Core: core, limiter: limiter, counter: counter}
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return newRateLimitCore(c.Core.With(fields), c.limiter, c.counter)
+}
+
+// Check takes a token for the entries the wrapped core writes, the entries it filters out by their
+// sink or named level don't consume the rate.
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	checked := c.Core.Check(ent, nil)
+	if checked == nil {
+		return ce
+	}
+
+	key := ent.Level.String() + "\x00" + ent.LoggerName + "\x00" + ent.Message
+	if !c.limiter.allow(key, ent.Time) {
+		c.counter.add()
+		return ce
+	}
+	checked.ErrorOutput = stderrOutput
+	return ce.AddCore(ent, checkedCore{checked: checked})
+}
+
+// checkedCore writes an entry checked against the cores wrapped by another core.
+type checkedCore struct {
+	checked *zapcore.CheckedEntry
+}
+
+func (c checkedCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c checkedCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c checkedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c checkedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// The caller and stack are added to the entry after Check
+	c.checked.Entry = ent
+	c.checked.Write(fields...)
+	return nil
+}
+
+func (c checkedCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,126 @@
+package logutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := make(map[string]interface{})
+		require.Nil(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		Sinks:    []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)},
+		Sampling: &SamplingConfig{Tick: time.Minute, Initial: 2, Thereafter: 4},
+	})
+	require.Nil(t, err)
+
+	for i := 0; i < 10; i++ {
+		l.Warnw("hot loop", "i", i)
+	}
+	l.Info("other")
+	require.Nil(t, l.Sync())
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 6)
+	require.Equal(t, float64(0), entries[0]["i"])
+	require.Equal(t, float64(1), entries[1]["i"])
+	require.Equal(t, float64(5), entries[2]["i"])
+	require.Equal(t, float64(9), entries[3]["i"])
+	require.Equal(t, "other", entries[4]["msg"])
+	require.Equal(t, droppedEntriesMessage, entries[5]["msg"])
+	require.Equal(t, float64(6), entries[5]["dropped"])
+}
+
+func TestRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		Sinks:     []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)},
+		RateLimit: &RateLimitConfig{Rate: 0.001, Burst: 3},
+	})
+	require.Nil(t, err)
+
+	for i := 0; i < 10; i++ {
+		l.Named("worker").Warn("retrying")
+	}
+	l.Warn("retrying")
+	require.Nil(t, l.Sync())
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 5)
+	require.Equal(t, "worker", entries[2]["logger"])
+	require.NotContains(t, entries[3], "logger")
+	require.Equal(t, float64(7), entries[4]["dropped"])
+
+	// Entries filtered out by their named level neither consume the rate nor count as dropped
+	buf.Reset()
+	l.(NamedLeveler).SetNamedLevel("quiet", ErrorLevel)
+	for i := 0; i < 10; i++ {
+		l.Named("quiet").Warn("filtered")
+	}
+	l.(NamedLeveler).SetNamedLevel("quiet", WarnLevel)
+	l.Named("quiet").Warn("filtered")
+	require.Nil(t, l.Sync())
+
+	entries = decodeLines(t, &buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, "filtered", entries[0]["msg"])
+}
+
+func TestDropReportInterval(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	close(w.release)
+	l, err := NewLoggerE(LoggerConfig{
+		Sinks:              []Sink{NewWriterSink("buf", w, DebugLevel, JSONEncoding)},
+		Sampling:           &SamplingConfig{Tick: time.Minute, Initial: 1},
+		DropReportInterval: 50 * time.Millisecond,
+	})
+	require.Nil(t, err)
+
+	// The drops are reported without further logging or Sync
+	for i := 0; i < 5; i++ {
+		l.Warn("hot loop")
+	}
+	require.Eventually(t, func() bool {
+		return strings.Contains(w.String(), `"msg":"dropped log entries","dropped":4`)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDropCounter(t *testing.T) {
+	now := time.Now()
+	c := newDropCounter(time.Minute, "")
+	c.lastReport.Store(now.UnixNano())
+
+	c.add()
+	c.add()
+	require.Zero(t, c.take(now.Add(time.Second), false))
+	require.Equal(t, uint64(2), c.take(now.Add(time.Minute), false))
+	require.Zero(t, c.take(now.Add(2*time.Minute), true))
+}
+
+func TestSamplingFromEnv(t *testing.T) {
+	t.Setenv("LOG_SAMPLING_INITIAL", "100")
+	t.Setenv("LOG_SAMPLING_TICK", "5s")
+
+	config, err := LoggerConfigFromEnv("LOG")
+	require.Nil(t, err)
+	require.Nil(t, config.RateLimit)
+	require.Equal(t, &SamplingConfig{Tick: 5 * time.Second, Initial: 100}, config.Sampling)
+}
@@ -0,0 +1,89 @@
+//go:build !windows
+
+package logutil
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// InstallSignalHandlers makes l react to signals until the returned func is called:
+//
+//   - SIGUSR1 lowers the console and file levels by one step, e.g. from info to debug. Signals
+//     received at DebugLevel cycle back to the levels at installation.
+//   - SIGUSR2 restores the levels at installation.
+//   - SIGHUP rotates the log file, so the file moved away by an external logrotate is reopened.
+//
+// The level signals are ignored when l is not a LevelReporter, SIGHUP when it is not a Rotator. The handlers are opt-in since they
+// take the signals over from the default behavior.
+func InstallSignalHandlers(l Logger) (stop func()) {
+	var consoleLevel, fileLevel LogLevel
+	if levels, ok := l.(LevelReporter); ok {
+		consoleLevel, fileLevel = levels.ConsoleLevel(), levels.FileLevel()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGHUP)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case sig := <-signals:
+				handleSignal(l, sig, consoleLevel, fileLevel)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		<-stopped
+	}
+}
+
+func handleSignal(l Logger, sig os.Signal, consoleLevel, fileLevel LogLevel) {
+	levels, ok := l.(LevelReporter)
+	switch sig {
+	case syscall.SIGUSR1:
+		if !ok {
+			return
+		}
+		if levels.ConsoleLevel() == DebugLevel && levels.FileLevel() == DebugLevel {
+			l.SetConsoleLevel(consoleLevel)
+			l.SetFileLevel(fileLevel)
+		} else {
+			l.SetConsoleLevel(verboseLevel(levels.ConsoleLevel()))
+			l.SetFileLevel(verboseLevel(levels.FileLevel()))
+		}
+		l.Infow("logger levels changed", "signal", sig.String(), "console_level", levels.ConsoleLevel(), "file_level", levels.FileLevel())
+	case syscall.SIGUSR2:
+		if !ok {
+			return
+		}
+		l.SetConsoleLevel(consoleLevel)
+		l.SetFileLevel(fileLevel)
+		l.Infow("logger levels restored", "signal", sig.String(), "console_level", consoleLevel, "file_level", fileLevel)
+	case syscall.SIGHUP:
+		rotator, ok := l.(Rotator)
+		if !ok {
+			return
+		}
+		if err := rotator.Rotate(); err != nil {
+			l.Errorw("could not rotate log file", "signal", sig.String(), "err", err)
+		}
+	}
+}
+
+// verboseLevel returns the level one step more verbose than level.
+func verboseLevel(level LogLevel) LogLevel {
+	if level == DebugLevel {
+		return DebugLevel
+	}
+	return level - 1
+}
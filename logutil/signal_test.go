@@ -0,0 +1,56 @@
+//go:build !windows
+
+package logutil
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignalHandlers(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLoggerE(LoggerConfig{
+		ConsoleLevel: WarnLevel,
+		FileEnabled:  true,
+		FileLevel:    InfoLevel,
+		LogDirectory: dir,
+		Filename:     "app.log",
+	})
+	require.Nil(t, err)
+
+	stop := InstallSignalHandlers(l)
+	defer stop()
+
+	reporter := l.(LevelReporter)
+	levels := func(console, file LogLevel) func() bool {
+		return func() bool {
+			return reporter.ConsoleLevel() == console && reporter.FileLevel() == file
+		}
+	}
+
+	require.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, levels(InfoLevel, DebugLevel), time.Second, 5*time.Millisecond)
+	require.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, levels(DebugLevel, DebugLevel), time.Second, 5*time.Millisecond)
+	require.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, levels(WarnLevel, InfoLevel), time.Second, 5*time.Millisecond)
+
+	require.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, levels(InfoLevel, DebugLevel), time.Second, 5*time.Millisecond)
+	require.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+	require.Eventually(t, levels(WarnLevel, InfoLevel), time.Second, 5*time.Millisecond)
+
+	// Simulate logrotate moving the file away
+	require.Nil(t, os.Rename(filepath.Join(dir, "app.log"), filepath.Join(dir, "app.log.1")))
+	require.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	require.Eventually(t, func() bool {
+		l.Info("after rotation")
+		_, err := os.Stat(filepath.Join(dir, "app.log"))
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+}
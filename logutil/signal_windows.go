@@ -0,0 +1,6 @@
+package logutil
+
+// InstallSignalHandlers does nothing on Windows, which lacks SIGUSR1 and SIGUSR2.
+func InstallSignalHandlers(l Logger) (stop func()) {
+	return func() {}
+}
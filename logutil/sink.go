@@ -0,0 +1,275 @@
+package logutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// ConsoleSinkName is the name of the sink built from the console options of the LoggerConfig.
+	ConsoleSinkName = "console"
+	// FileSinkName is the name of the sink built from the file options of the LoggerConfig.
+	FileSinkName = "file"
+)
+
+// Sink is an output of a Logger. Every sink has its own level, encoding and writer, a Logger
+// fans out each entry to all of its sinks.
+type Sink struct {
+	// Name identifies the sink in SetSinkLevel and SinkLevel. Names must be unique within a Logger,
+	// ConsoleSinkName and FileSinkName are reserved.
+	Name string
+	// Level is the initial level of the sink
+	Level LogLevel
+	// Encoding of the entries written to Writer
+	Encoding Encoding
+	// Color enables colored levels for the TextEncoding
+	Color bool
+	// Writer receives the encoded entries. Writers implementing zapcore.WriteSyncer are synced on Sync.
+	Writer io.Writer
+	// TimeFormat of the entries, one of the TimeFormat constants or a time.Format layout. Defaults to ISO8601.
+	TimeFormat string
+	// TimeZone of the entries, "local", "utc" or an IANA time zone name. Defaults to local time.
+	TimeZone string
+	// DurationFormat of the durations in the entries, one of the DurationFormat constants. Defaults to nanoseconds.
+	DurationFormat string
+
+	// newCore builds the core of sinks which don't simply write encoded entries to Writer.
+	newCore func(ws zapcore.WriteSyncer, config LoggerConfig) zapcore.Core
+	// closer releases the writer created by the sink constructors when the logger is closed
+	closer io.Closer
+}
+
+// NewWriterSink returns a Sink writing entries to w.
+func NewWriterSink(name string, w io.Writer, level LogLevel, encoding Encoding) Sink {
+	return Sink{
+		Name:     name,
+		Level:    level,
+		Encoding: encoding,
+		Writer:   w,
+	}
+}
+
+// NewStdoutSink returns a Sink named "stdout" writing entries to the standard output.
+func NewStdoutSink(level LogLevel, encoding Encoding) Sink {
+	return NewWriterSink("stdout", os.Stdout, level, encoding)
+}
+
+// NewNetworkSink returns a Sink writing entries to a connection to address on the named network,
+// e.g. "tcp" or "unix". The connection is re-established when a write fails.
+func NewNetworkSink(name, network, address string, level LogLevel, encoding Encoding) (Sink, error) {
+	w, err := newNetWriter(network, address)
+	if err != nil {
+		return Sink{}, err
+	}
+	sink := NewWriterSink(name, w, level, encoding)
+	sink.closer = w
+	return sink, nil
+}
+
+// NewCoreSink returns a Sink passing entries to core, e.g. to record or forward them. The level of
+// the sink decides which entries reach core, core should accept every level.
+func NewCoreSink(name string, level LogLevel, core zapcore.Core) Sink {
+	return Sink{
+		Name:  name,
+		Level: level,
+		newCore: func(zapcore.WriteSyncer, LoggerConfig) zapcore.Core {
+			return core
+		},
+	}
+}
+
+// validate reports the problems of the sink.
+func (s Sink) validate() error {
+	var errs []error
+	if s.Name == "" {
+		errs = append(errs, errors.New("sink name is required"))
+	}
+	if s.Level > FatalLevel {
+		errs = append(errs, fmt.Errorf("invalid level of sink %q: %d", s.Name, s.Level))
+	}
+	if s.Encoding > LogfmtEncoding {
+		errs = append(errs, fmt.Errorf("invalid encoding of sink %q: %d", s.Name, s.Encoding))
+	}
+	if err := s.timeOptions().validate(fmt.Sprintf("sink %q", s.Name)); err != nil {
+		errs = append(errs, err)
+	}
+	if s.Writer == nil && s.newCore == nil {
+		errs = append(errs, fmt.Errorf("writer of sink %q is required", s.Name))
+	}
+	return errors.Join(errs...)
+}
+
+// buildCore returns the core writing to the sink. The core accepts every level.
+func (s Sink) buildCore(config LoggerConfig) zapcore.Core {
+	var ws zapcore.WriteSyncer
+	if s.Writer != nil {
+		ws = zapcore.Lock(writeSyncer(s.Writer))
+	}
+
+	if s.newCore != nil {
+		return s.newCore(ws, config)
+	}
+	return zapcore.NewCore(newEncoder(s.Encoding, s.Color, s.timeOptions()), ws, zapcore.DebugLevel)
+}
+
+func (s Sink) timeOptions() timeOptions {
+	return timeOptions{format: s.TimeFormat, zone: s.TimeZone, duration: s.DurationFormat}
+}
+
+func writeSyncer(w io.Writer) zapcore.WriteSyncer {
+	if ws, ok := w.(zapcore.WriteSyncer); ok {
+		return ws
+	}
+	return zapcore.AddSync(w)
+}
+
+// netWriter writes to a network connection, reconnecting when a write fails. Writes time out
+// after netWriteTimeout so that a stalled peer doesn't block logging. Reconnections happen outside
+// the lock, writes fail right away while one is in progress or until the retry delay has passed.
+type netWriter struct {
+	network string
+	address string
+
+	mu         sync.Mutex // guards the fields below
+	conn       net.Conn
+	closed     bool
+	dialing    bool
+	retryDelay time.Duration
+	retryAt    time.Time
+
+	writeMu sync.Mutex // serializes the writes to conn
+}
+
+const (
+	netDialTimeout = 5 * time.Second
+	// netRetryDelay is the delay after a failed reconnection, doubled up to netMaxRetryDelay
+	netRetryDelay    = 100 * time.Millisecond
+	netMaxRetryDelay = 30 * time.Second
+)
+
+// netWriteTimeout is a variable for the tests
+var netWriteTimeout = 5 * time.Second
+
+var errNetWriterClosed = errors.New("network writer is closed")
+
+func newNetWriter(network, address string) (*netWriter, error) {
+	w := &netWriter{network: network, address: address}
+	conn, err := w.dial()
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+func (w *netWriter) dial() (net.Conn, error) {
+	conn, err := net.DialTimeout(w.network, w.address, netDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s %s. err: %v", w.network, w.address, err)
+	}
+	return conn, nil
+}
+
+// Write writes p, reconnecting once when the write fails. Only the bytes left out by the failed
+// write are sent on the new connection.
+func (w *netWriter) Write(p []byte) (int, error) {
+	written := 0
+	for attempt := 0; ; attempt++ {
+		conn, err := w.connection()
+		if err != nil {
+			return written, err
+		}
+		n, err := w.write(conn, p[written:])
+		written += n
+		if err == nil {
+			return written, nil
+		}
+		w.drop(conn)
+		if attempt > 0 {
+			return written, err
+		}
+	}
+}
+
+// connection returns the current connection, dialing a new one without holding the lock.
+func (w *netWriter) connection() (net.Conn, error) {
+	w.mu.Lock()
+	switch {
+	case w.closed:
+		w.mu.Unlock()
+		return nil, errNetWriterClosed
+	case w.conn != nil:
+		conn := w.conn
+		w.mu.Unlock()
+		return conn, nil
+	case w.dialing || time.Now().Before(w.retryAt):
+		w.mu.Unlock()
+		return nil, fmt.Errorf("could not write to %s %s. err: reconnecting", w.network, w.address)
+	}
+	w.dialing = true
+	w.mu.Unlock()
+
+	conn, err := w.dial()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dialing = false
+	if err != nil {
+		w.retryDelay = min(max(2*w.retryDelay, netRetryDelay), netMaxRetryDelay)
+		w.retryAt = time.Now().Add(w.retryDelay)
+		return nil, err
+	}
+	if w.closed {
+		conn.Close()
+		return nil, errNetWriterClosed
+	}
+	w.retryDelay, w.retryAt = 0, time.Time{}
+	w.conn = conn
+	return conn, nil
+}
+
+func (w *netWriter) write(conn net.Conn, p []byte) (int, error) {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(netWriteTimeout)); err != nil {
+		return 0, err
+	}
+	return conn.Write(p)
+}
+
+// drop closes conn after a failed write, unless it was already replaced.
+func (w *netWriter) drop(conn net.Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == conn {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+func (w *netWriter) Sync() error {
+	return nil
+}
+
+// Close closes the connection, later writes fail.
+func (w *netWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
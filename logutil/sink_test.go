@@ -0,0 +1,157 @@
+package logutil
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSinks(t *testing.T) {
+	var jsonBuf, textBuf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		Sinks: []Sink{
+			NewWriterSink("json", &jsonBuf, DebugLevel, JSONEncoding),
+			NewWriterSink("text", &textBuf, WarnLevel, TextEncoding),
+		},
+	})
+	require.Nil(t, err)
+
+	l.Debugw("debug entry", "key", "value")
+	l.Warn("warn entry")
+	sinks := l.(SinkLeveler)
+	require.Nil(t, sinks.SetSinkLevel("json", ErrorLevel))
+	l.Warn("second warn entry")
+
+	require.Equal(t, 2, strings.Count(jsonBuf.String(), "\n"))
+	require.Contains(t, jsonBuf.String(), `"key":"value"`)
+	require.Equal(t, 2, strings.Count(textBuf.String(), "\n"))
+	require.Contains(t, textBuf.String(), "WARN\twarn entry")
+
+	level, ok := sinks.SinkLevel("json")
+	require.True(t, ok)
+	require.Equal(t, ErrorLevel, level)
+	require.NotNil(t, sinks.SetSinkLevel("unknown", InfoLevel))
+}
+
+func TestSinkValidation(t *testing.T) {
+	_, err := NewLoggerE(LoggerConfig{
+		Sinks: []Sink{
+			NewWriterSink("console", &bytes.Buffer{}, InfoLevel, JSONEncoding),
+			{Name: "nowriter"},
+		},
+	})
+	require.ErrorContains(t, err, `duplicate sink name: "console"`)
+	require.ErrorContains(t, err, `writer of sink "nowriter" is required`)
+
+	// NewLogger drops the duplicate and reports it through the DefaultLogger
+	var report bytes.Buffer
+	defaultLogger := DefaultLogger
+	DefaultLogger, err = NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("report", &report, DebugLevel, JSONEncoding)}})
+	require.Nil(t, err)
+	defer func() { DefaultLogger = defaultLogger }()
+
+	l := NewLogger(LoggerConfig{
+		Sinks: []Sink{
+			NewWriterSink("b", &bytes.Buffer{}, InfoLevel, JSONEncoding),
+			NewWriterSink("b", &bytes.Buffer{}, InfoLevel, JSONEncoding),
+		},
+	})
+	require.NotNil(t, l)
+	require.Contains(t, report.String(), `could not create logger. err: duplicate sink name: \"b\"`)
+}
+
+func TestNetworkSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+
+	lines := make(chan string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	sink, err := NewNetworkSink("tcp", "tcp", listener.Addr().String(), InfoLevel, JSONEncoding)
+	require.Nil(t, err)
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{sink}})
+	require.Nil(t, err)
+
+	l.Infow("over the wire", "n", 1)
+	require.Contains(t, <-lines, `"msg":"over the wire","n":1`)
+}
+
+func TestNetworkSinkStalledPeer(t *testing.T) {
+	defer func(timeout time.Duration) { netWriteTimeout = timeout }(netWriteTimeout)
+	netWriteTimeout = 100 * time.Millisecond
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+
+	// The peer accepts connections but never reads
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	w, err := newNetWriter("tcp", listener.Addr().String())
+	require.Nil(t, err)
+	defer w.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.Write(make([]byte, 64<<20))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("write to a stalled peer did not time out")
+	}
+}
+
+func TestNetworkSinkReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	address := listener.Addr().String()
+
+	w, err := newNetWriter("tcp", address)
+	require.Nil(t, err)
+
+	// Without a peer, the reconnection fails and the next writes fail right away until the retry delay
+	listener.Close()
+	w.drop(w.conn)
+	_, err = w.Write([]byte("lost\n"))
+	require.ErrorContains(t, err, "could not connect")
+	_, err = w.Write([]byte("lost\n"))
+	require.ErrorContains(t, err, "reconnecting")
+
+	listener, err = net.Listen("tcp", address)
+	require.Nil(t, err)
+	defer listener.Close()
+	time.Sleep(netRetryDelay)
+	_, err = w.Write([]byte("sent\n"))
+	require.Nil(t, err)
+
+	require.Nil(t, w.Close())
+	_, err = w.Write([]byte("late\n"))
+	require.Equal(t, errNetWriterClosed, err)
+}
@@ -0,0 +1,293 @@
+package logutil
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SlogSinkName is the name of the sink of the loggers created by NewLoggerFromSlog.
+const SlogSinkName = "slog"
+
+// slogLevel maps the level to the slog level. PanicLevel and FatalLevel map above slog.LevelError.
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	case PanicLevel:
+		return slog.LevelError + 4
+	default:
+		return slog.LevelError + 8
+	}
+}
+
+// logLevelFromSlog maps the slog level to the closest level at or below it.
+func logLevelFromSlog(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
+
+// slogHandler is a slog.Handler logging records through a Logger.
+type slogHandler struct {
+	logger Logger
+	// prefix of the attribute keys, the open groups joined by periods
+	prefix string
+}
+
+// NewSlogHandler returns a slog.Handler logging records through l. Levels are mapped to the closest
+// LogLevel at or below them, attributes become fields and groups prefix the keys of their attributes
+// separated by periods. Fields extracted from the context of the records are added as by the Ctx methods.
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return enabled(h.logger, logLevelFromSlog(level))
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := attrsToFields(nil, h.prefix, attrs)
+	if len(fields) == 0 {
+		return h
+	}
+	return &slogHandler{logger: h.logger.WithFields(fields...), prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := sweetenFields(contextFields(ctx))
+	r.Attrs(func(attr slog.Attr) bool {
+		fields = attrsToFields(fields, h.prefix, []slog.Attr{attr})
+		return true
+	})
+	level := logLevelFromSlog(r.Level)
+
+	ll, ok := h.logger.(*logger)
+	if !ok {
+		args := make([]interface{}, len(fields))
+		for i, field := range fields {
+			args[i] = field
+		}
+		switch level {
+		case DebugLevel:
+			h.logger.Debugw(r.Message, args...)
+		case InfoLevel:
+			h.logger.Infow(r.Message, args...)
+		case WarnLevel:
+			h.logger.Warnw(r.Message, args...)
+		default:
+			h.logger.Errorw(r.Message, args...)
+		}
+		return nil
+	}
+
+	// Write to the core directly to keep the time and the caller of the record
+	ent := zapcore.Entry{
+		Level:      level.zapLevel(),
+		Time:       r.Time,
+		LoggerName: ll.unsugared.Name(),
+		Message:    r.Message,
+	}
+	if ent.Time.IsZero() {
+		ent.Time = time.Now()
+	}
+	ce := ll.unsugared.Core().Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+	if ll.callerEnabled && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.EntryCaller{
+			Defined:  true,
+			PC:       frame.PC,
+			File:     frame.File,
+			Line:     frame.Line,
+			Function: frame.Function,
+		}
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+// attrsToFields appends the attributes to fields, prefixing their keys and flattening groups.
+func attrsToFields(fields []Field, prefix string, attrs []slog.Attr) []Field {
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		if value.Kind() == slog.KindGroup {
+			groupPrefix := prefix
+			if attr.Key != "" {
+				groupPrefix += attr.Key + "."
+			}
+			fields = attrsToFields(fields, groupPrefix, value.Group())
+			continue
+		}
+		if attr.Key == "" {
+			continue
+		}
+
+		key := prefix + attr.Key
+		switch value.Kind() {
+		case slog.KindString:
+			fields = append(fields, zap.String(key, value.String()))
+		case slog.KindInt64:
+			fields = append(fields, zap.Int64(key, value.Int64()))
+		case slog.KindUint64:
+			fields = append(fields, zap.Uint64(key, value.Uint64()))
+		case slog.KindFloat64:
+			fields = append(fields, zap.Float64(key, value.Float64()))
+		case slog.KindBool:
+			fields = append(fields, zap.Bool(key, value.Bool()))
+		case slog.KindDuration:
+			fields = append(fields, zap.Duration(key, value.Duration()))
+		case slog.KindTime:
+			fields = append(fields, zap.Time(key, value.Time()))
+		default:
+			fields = append(fields, zap.Any(key, value.Any()))
+		}
+	}
+	return fields
+}
+
+// sweetenFields converts loosely typed key-value pairs to fields. Fields are kept as they are,
+// a key without a value or a non-string key is logged under "!BADKEY".
+func sweetenFields(keysAndValues []interface{}) []Field {
+	var fields []Field
+	for i := 0; i < len(keysAndValues); i++ {
+		switch key := keysAndValues[i].(type) {
+		case Field:
+			fields = append(fields, key)
+		case string:
+			if i == len(keysAndValues)-1 {
+				fields = append(fields, zap.String("!BADKEY", key))
+				continue
+			}
+			fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+			i++
+		default:
+			fields = append(fields, zap.Any("!BADKEY", key))
+		}
+	}
+	return fields
+}
+
+// NewSlogSink returns a Sink passing entries to handler as slog records. The logger name is added
+// as the "logger" attribute and namespaces become groups.
+func NewSlogSink(name string, handler slog.Handler, level LogLevel) Sink {
+	return NewCoreSink(name, level, &slogCore{handler: handler})
+}
+
+// NewLoggerFromSlog returns a Logger writing into handler. The handler decides which levels are
+// enabled, the level of the SlogSinkName sink can narrow them further.
+func NewLoggerFromSlog(handler slog.Handler) Logger {
+	return NewLogger(LoggerConfig{
+		Sinks:         []Sink{NewSlogSink(SlogSinkName, handler, DebugLevel)},
+		CallerEnabled: true,
+	})
+}
+
+// slogCore writes entries to a slog.Handler.
+type slogCore struct {
+	handler slog.Handler
+}
+
+func (c *slogCore) Enabled(level zapcore.Level) bool {
+	return c.handler.Enabled(context.Background(), slogLevel(fromZapLevel(level)))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	handler := c.handler
+	for i, field := range fields {
+		if field.Type == zapcore.NamespaceType {
+			if attrs := fieldsToAttrs(fields[:i]); len(attrs) > 0 {
+				handler = handler.WithAttrs(attrs)
+			}
+			return (&slogCore{handler: handler.WithGroup(field.Key)}).With(fields[i+1:])
+		}
+	}
+	if attrs := fieldsToAttrs(fields); len(attrs) > 0 {
+		handler = handler.WithAttrs(attrs)
+	}
+	return &slogCore{handler: handler}
+}
+
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(ent.Time, slogLevel(fromZapLevel(ent.Level)), ent.Message, ent.Caller.PC)
+	if ent.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", ent.LoggerName))
+	}
+	if ent.Stack != "" {
+		r.AddAttrs(slog.String("stacktrace", ent.Stack))
+	}
+	r.AddAttrs(fieldsToAttrs(fields)...)
+	return c.handler.Handle(context.Background(), r)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+// fieldsToAttrs converts fields to attributes, nesting the fields following a namespace in a group.
+func fieldsToAttrs(fields []zapcore.Field) []slog.Attr {
+	var attrs []slog.Attr
+	for i, field := range fields {
+		if field.Type == zapcore.NamespaceType {
+			return append(attrs, slog.Attr{Key: field.Key, Value: slog.GroupValue(fieldsToAttrs(fields[i+1:])...)})
+		}
+
+		enc := zapcore.NewMapObjectEncoder()
+		field.AddTo(enc)
+		attrs = append(attrs, mapToAttrs(enc.Fields)...)
+	}
+	return attrs
+}
+
+// mapToAttrs converts the values encoded by zapcore.MapObjectEncoder, sorted by key.
+func mapToAttrs(m map[string]interface{}) []slog.Attr {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(m))
+	for _, key := range keys {
+		if nested, ok := m[key].(map[string]interface{}); ok {
+			attrs = append(attrs, slog.Attr{Key: key, Value: slog.GroupValue(mapToAttrs(nested)...)})
+			continue
+		}
+		attrs = append(attrs, slog.Any(key, m[key]))
+	}
+	return attrs
+}
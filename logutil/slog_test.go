@@ -0,0 +1,76 @@
+package logutil
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		Name:          "app",
+		Sinks:         []Sink{NewWriterSink("json", &buf, InfoLevel, JSONEncoding)},
+		CallerEnabled: true,
+	})
+	require.Nil(t, err)
+
+	sl := slog.New(NewSlogHandler(l))
+	require.False(t, sl.Enabled(context.Background(), slog.LevelDebug))
+	sl.Debug("hidden")
+
+	ctx := ContextWithFields(context.Background(), "request_id", "abc")
+	line := nextLine()
+	sl.With("a", 1).WithGroup("req").InfoContext(ctx, "hello",
+		"id", 7, slog.Group("user", "name", "bob"), slog.Group("", "inline", true))
+	sl.Log(context.Background(), slog.LevelWarn+1, "custom level")
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+
+	require.Equal(t, "info", entries[0]["level"])
+	require.Equal(t, "app", entries[0]["logger"])
+	require.Equal(t, "hello", entries[0]["msg"])
+	require.Equal(t, float64(1), entries[0]["a"])
+	require.Equal(t, "abc", entries[0]["request_id"])
+	require.Equal(t, float64(7), entries[0]["req.id"])
+	require.Equal(t, "bob", entries[0]["req.user.name"])
+	require.Equal(t, true, entries[0]["req.inline"])
+	require.Equal(t, "logutil/slog_test.go:"+line, entries[0]["caller"])
+
+	require.Equal(t, "warn", entries[1]["level"])
+}
+
+func TestLoggerFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLoggerFromSlog(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	l.Named("db").Infow("hidden")
+	l.Named("db").With("table", "users").WithFields(zap.Namespace("query"), String("op", "select")).
+		Warnw("slow", "ms", 5)
+	l.Errorw("failed", "err", "boom")
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+
+	require.Equal(t, "WARN", entries[0]["level"])
+	require.Equal(t, "slow", entries[0]["msg"])
+	require.Equal(t, "users", entries[0]["table"])
+	require.Equal(t, map[string]interface{}{"op": "select", "logger": "db", "ms": float64(5)}, entries[0]["query"])
+
+	require.Equal(t, "ERROR", entries[1]["level"])
+	require.Equal(t, "boom", entries[1]["err"])
+	require.NotContains(t, entries[1], "logger")
+}
+
+func TestSlogLevels(t *testing.T) {
+	for level := DebugLevel; level <= ErrorLevel; level++ {
+		require.Equal(t, level, logLevelFromSlog(slogLevel(level)))
+	}
+	require.Equal(t, ErrorLevel, logLevelFromSlog(slogLevel(FatalLevel)))
+	require.Equal(t, DebugLevel, logLevelFromSlog(slog.LevelDebug-4))
+}
@@ -0,0 +1,115 @@
+package logutil
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sync"
+)
+
+const (
+	// lineWriterDepth is the number of frames between the caller of LineWriter.Write and the Logger.
+	lineWriterDepth = 3
+	// stdLogDepth is the number of frames of the standard log package between its caller and Write.
+	stdLogDepth = 2
+)
+
+// LineWriter is an io.Writer logging each written line as an entry. Lines may be split across
+// writes, the trailing newline and carriage return are removed from the messages.
+type LineWriter struct {
+	mu     sync.Mutex
+	logger Logger
+	level  LogLevel
+	buf    []byte
+}
+
+// NewLineWriter returns a LineWriter logging the lines through l at level. Levels above
+// ErrorLevel are lowered to ErrorLevel, so writes never panic or exit.
+func NewLineWriter(l Logger, level LogLevel) *LineWriter {
+	return newLineWriter(l, level, 0)
+}
+
+func newLineWriter(l Logger, level LogLevel, skip int) *LineWriter {
+	if level > ErrorLevel {
+		level = ErrorLevel
+	}
+	if s, ok := l.(interface{ withCallerSkip(int) Logger }); ok {
+		l = s.withCallerSkip(lineWriterDepth + skip)
+	}
+	return &LineWriter{logger: l, level: level}
+}
+
+// Write logs the complete lines of p and keeps the incomplete last line until the next Write or Flush.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.logLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) == 0 {
+		// Release the consumed buffer
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Flush logs the incomplete last line, if any.
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.logLine(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *LineWriter) logLine(line []byte) {
+	logAt(w.logger, w.level, string(bytes.TrimSuffix(line, []byte("\r"))))
+}
+
+// logAt logs the message and key-value pairs at level, levels above ErrorLevel log at ErrorLevel.
+func logAt(l Logger, level LogLevel, msg string, keysAndValues ...interface{}) {
+	switch level {
+	case DebugLevel:
+		l.Debugw(msg, keysAndValues...)
+	case InfoLevel:
+		l.Infow(msg, keysAndValues...)
+	case WarnLevel:
+		l.Warnw(msg, keysAndValues...)
+	default:
+		l.Errorw(msg, keysAndValues...)
+	}
+}
+
+// RedirectStdLog redirects the output of the standard log package to l at level until the returned
+// func restores the previous output, flags and prefix. The flags and prefix are cleared meanwhile,
+// l adds its own timestamp.
+func RedirectStdLog(l Logger, level LogLevel) (func(), error) {
+	if level > ErrorLevel {
+		return nil, fmt.Errorf("invalid standard log level: %s", level)
+	}
+
+	flags := log.Flags()
+	prefix := log.Prefix()
+	output := log.Writer()
+
+	w := newLineWriter(l, level, stdLogDepth)
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(w)
+
+	return func() {
+		w.Flush()
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		log.SetOutput(output)
+	}, nil
+}
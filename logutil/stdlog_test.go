@@ -0,0 +1,76 @@
+package logutil
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineWriter(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		CallerEnabled: true,
+		Sinks:         []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)},
+	})
+	require.Nil(t, err)
+
+	w := NewLineWriter(l, WarnLevel)
+	line := nextLine()
+	_, err = w.Write([]byte("first\r\nsec"))
+	require.Nil(t, err)
+	fmt.Fprint(w, "ond\nthird")
+	require.Len(t, decodeLines(t, &buf), 2)
+	w.Flush()
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 3)
+	require.Equal(t, "first", entries[0]["msg"])
+	require.Equal(t, "warn", entries[0]["level"])
+	require.Equal(t, "logutil/stdlog_test.go:"+line, entries[0]["caller"])
+	require.Equal(t, "second", entries[1]["msg"])
+	require.Equal(t, "third", entries[2]["msg"])
+
+	buf.Reset()
+	fmt.Fprintln(NewLineWriter(l, FatalLevel), "not fatal")
+	require.Equal(t, "error", decodeLines(t, &buf)[0]["level"])
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{
+		CallerEnabled: true,
+		Sinks:         []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)},
+	})
+	require.Nil(t, err)
+
+	_, err = RedirectStdLog(l, PanicLevel)
+	require.NotNil(t, err)
+
+	log.SetPrefix("prefix: ")
+	restore, err := RedirectStdLog(l, InfoLevel)
+	require.Nil(t, err)
+
+	var lines []string
+	lines = append(lines, nextLine())
+	log.Printf("from %s", "std")
+	lines = append(lines, nextLine())
+	log.Default().Println("from default")
+	restore()
+
+	require.Equal(t, "prefix: ", log.Prefix())
+	require.Equal(t, os.Stderr, log.Writer())
+	log.SetPrefix("")
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+	require.Equal(t, "from std", entries[0]["msg"])
+	require.Equal(t, "info", entries[0]["level"])
+	require.Equal(t, "from default", entries[1]["msg"])
+	for i, entry := range entries {
+		require.Equal(t, "logutil/stdlog_test.go:"+lines[i], entry["caller"])
+	}
+}
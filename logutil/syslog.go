@@ -0,0 +1,255 @@
+package logutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SyslogFormat is the message format of the syslog sink.
+type SyslogFormat uint8
+
+const (
+	// RFC5424 is the structured syslog protocol.
+	RFC5424 SyslogFormat = iota
+	// RFC3164 is the legacy BSD syslog protocol.
+	RFC3164
+)
+
+// SyslogFacility is the facility code of syslog messages.
+type SyslogFacility uint8
+
+const (
+	FacilityKern SyslogFacility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLpr
+	FacilityNews
+	FacilityUucp
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFtp
+	FacilityLocal0 SyslogFacility = iota + 4
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// localSyslogAddresses are the unixgram sockets tried when no network is configured.
+var localSyslogAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogConfig configures the syslog sink
+type SyslogConfig struct {
+	// Name of the sink, defaults to "syslog"
+	Name string
+	// Level is the initial level of the sink
+	Level LogLevel
+	// Network is one of "udp", "tcp", "unixgram" or "unix". The local syslog socket is used when empty.
+	Network string
+	// Address of the syslog server
+	Address string
+	// Format of the messages, defaults to RFC5424
+	Format SyslogFormat
+	// Facility of the messages, defaults to FacilityUser when zero
+	Facility SyslogFacility
+	// Hostname reported in the messages, defaults to os.Hostname
+	Hostname string
+	// AppName reported in the messages, defaults to the Name of the LoggerConfig or the executable name
+	AppName string
+	// Encoding of the message body which carries the logger name, message and fields
+	Encoding Encoding
+	// DurationFormat of the durations in the message body, see the DurationFormat constants
+	DurationFormat string
+}
+
+// NewSyslogSink returns a Sink sending entries to a syslog server. Entries are framed by octet counting
+// for RFC5424 and by newlines for RFC3164 over stream connections, one entry per datagram otherwise.
+func NewSyslogSink(config SyslogConfig) (Sink, error) {
+	if config.Name == "" {
+		config.Name = "syslog"
+	}
+	if config.Format > RFC3164 {
+		return Sink{}, fmt.Errorf("unknown syslog format: %d", config.Format)
+	}
+	if config.Facility == FacilityKern {
+		config.Facility = FacilityUser
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	if err := (timeOptions{duration: config.DurationFormat}).validate("syslog"); err != nil {
+		return Sink{}, err
+	}
+
+	w, err := dialSyslog(config.Network, config.Address)
+	if err != nil {
+		return Sink{}, err
+	}
+	stream := w.network == "tcp" || w.network == "unix"
+
+	return Sink{
+		Name:     config.Name,
+		Level:    config.Level,
+		Encoding: config.Encoding,
+		Writer:   w,
+		closer:   w,
+		newCore: func(ws zapcore.WriteSyncer, loggerConfig LoggerConfig) zapcore.Core {
+			appName := config.AppName
+			if appName == "" {
+				appName = loggerConfig.Name
+			}
+			if appName == "" {
+				appName = filepath.Base(os.Args[0])
+			}
+
+			// Time and level are carried by the syslog header
+			encCfg := encoderConfig(config.Encoding, false, timeOptions{duration: config.DurationFormat})
+			encCfg.TimeKey = ""
+			encCfg.LevelKey = ""
+
+			return &syslogCore{
+				enc:      newEncoderWithConfig(config.Encoding, encCfg),
+				ws:       ws,
+				format:   config.Format,
+				facility: config.Facility,
+				hostname: syslogHeaderField(config.Hostname, 255),
+				appName:  syslogHeaderField(appName, 48),
+				procID:   strconv.Itoa(os.Getpid()),
+				stream:   stream,
+			}
+		},
+	}, nil
+}
+
+func dialSyslog(network, address string) (*netWriter, error) {
+	if network != "" {
+		return newNetWriter(network, address)
+	}
+
+	var errs []error
+	for _, address := range localSyslogAddresses {
+		w, err := newNetWriter("unixgram", address)
+		if err == nil {
+			return w, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("could not connect to local syslog. err: %v", errors.Join(errs...))
+}
+
+// syslogSeverity maps the entry level to the syslog severity.
+func syslogSeverity(level zapcore.Level) int {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return 7 // debug
+	case level == zapcore.InfoLevel:
+		return 6 // informational
+	case level == zapcore.WarnLevel:
+		return 4 // warning
+	case level == zapcore.ErrorLevel:
+		return 3 // error
+	case level <= zapcore.PanicLevel:
+		return 2 // critical
+	default:
+		return 1 // alert
+	}
+}
+
+// syslogHeaderField returns s as printable US-ASCII without spaces, truncated to max characters.
+// Empty values are replaced by the NILVALUE.
+func syslogHeaderField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// syslogCore formats entries as syslog messages.
+type syslogCore struct {
+	enc      zapcore.Encoder
+	ws       zapcore.WriteSyncer
+	format   SyslogFormat
+	facility SyslogFacility
+	hostname string
+	appName  string
+	procID   string
+	stream   bool
+}
+
+func (c *syslogCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, field := range fields {
+		field.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := strings.TrimRight(buf.String(), "\r\n")
+	buf.Free()
+
+	pri := int(c.facility)*8 + syslogSeverity(ent.Level)
+
+	var frame string
+	switch c.format {
+	case RFC3164:
+		frame = fmt.Sprintf("<%d>%s %s %s[%s]: %s",
+			pri, ent.Time.Format("Jan _2 15:04:05"), c.hostname, c.appName, c.procID, msg)
+		if c.stream {
+			frame += "\n"
+		}
+	default:
+		msgID := syslogHeaderField(ent.LoggerName, 32)
+		frame = fmt.Sprintf("<%d>1 %s %s %s %s %s - %s",
+			pri, ent.Time.Format("2006-01-02T15:04:05.000000Z07:00"), c.hostname, c.appName, c.procID, msgID, msg)
+		if c.stream {
+			frame = strconv.Itoa(len(frame)) + " " + frame
+		}
+	}
+
+	if _, err := c.ws.Write([]byte(frame)); err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *syslogCore) Sync() error {
+	return c.ws.Sync()
+}
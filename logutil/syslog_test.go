@@ -0,0 +1,70 @@
+package logutil
+
+import (
+	"bufio"
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer conn.Close()
+
+	sink, err := NewSyslogSink(SyslogConfig{
+		Network:  "udp",
+		Address:  conn.LocalAddr().String(),
+		Hostname: "host",
+		Facility: FacilityLocal0,
+		Encoding: JSONEncoding,
+	})
+	require.Nil(t, err)
+	l, err := NewLoggerE(LoggerConfig{Name: "my app", Sinks: []Sink{sink}})
+	require.Nil(t, err)
+
+	l.Named("db").Warnw("slow query", "ms", 250)
+
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	require.Nil(t, err)
+	// local0 (16) * 8 + warning (4) = 132
+	pattern := `^<132>1 \S+ host my_app \d+ my_app\.db - \{"logger":"my_app\.db","msg":"slow query","ms":250\}$`
+	require.Regexp(t, regexp.MustCompile(pattern), string(buf[:n]))
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+
+	lines := make(chan string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	sink, err := NewSyslogSink(SyslogConfig{
+		Network:  "tcp",
+		Address:  listener.Addr().String(),
+		Format:   RFC3164,
+		Hostname: "host",
+		AppName:  "app",
+	})
+	require.Nil(t, err)
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{sink}})
+	require.Nil(t, err)
+
+	l.Error("failed")
+	// user (1) * 8 + error (3) = 11
+	require.Regexp(t, regexp.MustCompile(`^<11>\w{3} [ \d]\d \d{2}:\d{2}:\d{2} host app\[\d+\]: failed$`), <-lines)
+}
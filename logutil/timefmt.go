@@ -0,0 +1,111 @@
+package logutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Time formats accepted by the TimeFormat options. Any other value is used as a time.Format layout.
+const (
+	TimeFormatISO8601     = "iso8601"
+	TimeFormatRFC3339     = "rfc3339"
+	TimeFormatRFC3339Nano = "rfc3339nano"
+	TimeFormatEpoch       = "epoch"
+	TimeFormatEpochMillis = "epoch_millis"
+	TimeFormatEpochNanos  = "epoch_nanos"
+)
+
+// Duration formats accepted by the DurationFormat options.
+const (
+	DurationFormatNanos   = "nanos"
+	DurationFormatMillis  = "millis"
+	DurationFormatSeconds = "seconds"
+	DurationFormatString  = "string"
+)
+
+// timeOptions configures how an output encodes times and durations. Empty values select the
+// defaults: ISO8601 times in local time and durations in nanoseconds.
+type timeOptions struct {
+	format   string
+	zone     string
+	duration string
+}
+
+// validate reports the invalid options, prefixing the messages with output.
+func (o timeOptions) validate(output string) error {
+	if _, err := o.timeEncoder(); err != nil {
+		return fmt.Errorf("invalid %s time options: %v", output, err)
+	}
+	if _, err := o.durationEncoder(); err != nil {
+		return fmt.Errorf("invalid %s duration format: %v", output, err)
+	}
+	return nil
+}
+
+// timeEncoder returns the encoder of the time format converting times to the time zone.
+func (o timeOptions) timeEncoder() (zapcore.TimeEncoder, error) {
+	var enc zapcore.TimeEncoder
+	switch strings.ToLower(o.format) {
+	case "", TimeFormatISO8601:
+		enc = zapcore.ISO8601TimeEncoder
+	case TimeFormatRFC3339:
+		enc = zapcore.RFC3339TimeEncoder
+	case TimeFormatRFC3339Nano:
+		enc = zapcore.RFC3339NanoTimeEncoder
+	case TimeFormatEpoch:
+		enc = zapcore.EpochTimeEncoder
+	case TimeFormatEpochMillis:
+		enc = zapcore.EpochMillisTimeEncoder
+	case TimeFormatEpochNanos:
+		enc = zapcore.EpochNanosTimeEncoder
+	default:
+		enc = zapcore.TimeEncoderOfLayout(o.format)
+	}
+
+	var loc *time.Location
+	switch {
+	case o.zone == "", strings.EqualFold(o.zone, "local"):
+		return enc, nil
+	case strings.EqualFold(o.zone, "utc"):
+		loc = time.UTC
+	default:
+		var err error
+		if loc, err = time.LoadLocation(o.zone); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(t time.Time, pae zapcore.PrimitiveArrayEncoder) {
+		enc(t.In(loc), pae)
+	}, nil
+}
+
+// durationEncoder returns the encoder of the duration format.
+func (o timeOptions) durationEncoder() (zapcore.DurationEncoder, error) {
+	switch strings.ToLower(o.duration) {
+	case "", DurationFormatNanos:
+		return zapcore.NanosDurationEncoder, nil
+	case DurationFormatMillis:
+		return zapcore.MillisDurationEncoder, nil
+	case DurationFormatSeconds:
+		return zapcore.SecondsDurationEncoder, nil
+	case DurationFormatString:
+		return zapcore.StringDurationEncoder, nil
+	default:
+		return nil, fmt.Errorf("unknown duration format: %q", o.duration)
+	}
+}
+
+// apply sets the time and duration encoders of config. Invalid options keep the defaults,
+// they are reported by validate.
+func (o timeOptions) apply(config *zapcore.EncoderConfig) {
+	if enc, err := o.timeEncoder(); err == nil {
+		config.EncodeTime = enc
+	}
+	if enc, err := o.durationEncoder(); err == nil {
+		config.EncodeDuration = enc
+	}
+}
@@ -0,0 +1,61 @@
+package logutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTimeFormats(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.FixedZone("X", 3600))
+
+	encode := func(opts timeOptions) string {
+		enc, err := opts.timeEncoder()
+		require.Nil(t, err)
+		arr := zapcore.NewMapObjectEncoder()
+		require.Nil(t, arr.AddArray("t", zapcore.ArrayMarshalerFunc(func(ae zapcore.ArrayEncoder) error {
+			enc(ts, ae)
+			return nil
+		})))
+		data, err := json.Marshal(arr.Fields["t"].([]interface{})[0])
+		require.Nil(t, err)
+		return string(data)
+	}
+
+	require.Equal(t, `"2024-05-06T06:08:09.123456789Z"`, encode(timeOptions{format: "RFC3339Nano", zone: "UTC"}))
+	require.Equal(t, "1714975689123.4568", encode(timeOptions{format: TimeFormatEpochMillis}))
+	require.Equal(t, `"06 May 24 06:08"`, encode(timeOptions{format: "02 Jan 06 15:04", zone: "utc"}))
+	require.Equal(t, `"2024-05-06T02:08:09-04:00"`, encode(timeOptions{format: TimeFormatRFC3339, zone: "America/New_York"}))
+
+	require.NotNil(t, timeOptions{zone: "Mars/Olympus"}.validate("console"))
+	require.NotNil(t, timeOptions{duration: "weeks"}.validate("console"))
+	require.Nil(t, timeOptions{}.validate("console"))
+}
+
+func TestSinkTimeOptions(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink("json", &buf, DebugLevel, JSONEncoding)
+	sink.TimeFormat = TimeFormatEpochNanos
+	sink.DurationFormat = DurationFormatString
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{sink}})
+	require.Nil(t, err)
+
+	l.Infow("done", "took", 1500*time.Millisecond)
+
+	var entry map[string]interface{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.IsType(t, float64(0), entry["ts"])
+	require.Equal(t, "1.5s", entry["took"])
+
+	sink.TimeZone = "Nowhere/Void"
+	_, err = NewLoggerE(LoggerConfig{Sinks: []Sink{sink}})
+	require.NotNil(t, err)
+
+	err = LoggerConfig{ConsoleTimeZone: "Nowhere/Void", FileDurationFormat: "weeks"}.Validate()
+	require.ErrorContains(t, err, "console")
+	require.ErrorContains(t, err, "file duration")
+}
@@ -0,0 +1,158 @@
+package logutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/justmumu/goutils/maputil"
+)
+
+type retryAttemptKey struct{}
+
+// ContextWithRetryAttempt returns a copy of ctx carrying the retry attempt of the requests sent with
+// it, logged by LoggingTransport. The first attempt is zero.
+func ContextWithRetryAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, retryAttemptKey{}, attempt)
+}
+
+// retryAttempt returns the retry attempt carried by ctx.
+func retryAttempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(retryAttemptKey{}).(int)
+	return attempt
+}
+
+// LoggingTransport is an http.RoundTripper logging the requests sent through Transport along with
+// their status, latency, error and retry attempt. Use NewLoggingTransport to get the default levels,
+// the levels of a zero LoggingTransport are DebugLevel.
+type LoggingTransport struct {
+	// Transport sends the requests, defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// Logger receives the entries, defaults to DefaultLogger
+	Logger Logger
+	// Level of the exchanges answered with a status below 400
+	Level LogLevel
+	// ClientErrorLevel of the exchanges answered with a 4xx status
+	ClientErrorLevel LogLevel
+	// ServerErrorLevel of the exchanges answered with a 5xx status
+	ServerErrorLevel LogLevel
+	// ErrorLevel of the requests failing without a response
+	ErrorLevel LogLevel
+	// DumpExchanges adds the exchange, as built by maputil.NewHTTPResponseMap, to the entries while
+	// the Logger is at DebugLevel. The Authorization, Proxy-Authorization, Cookie and Set-Cookie
+	// headers and the URL password are masked.
+	DumpExchanges bool
+	// MaxBodySize caps the dumped bodies in bytes, defaults to 4 KiB. Larger bodies are truncated in
+	// the dump and still sent and returned whole.
+	MaxBodySize int
+}
+
+// NewLoggingTransport returns a LoggingTransport sending requests through transport and logging them
+// through l at InfoLevel, WarnLevel for 4xx statuses and ErrorLevel for 5xx statuses and failures.
+func NewLoggingTransport(l Logger, transport http.RoundTripper) *LoggingTransport {
+	return &LoggingTransport{
+		Transport:        transport,
+		Logger:           l,
+		Level:            InfoLevel,
+		ClientErrorLevel: WarnLevel,
+		ServerErrorLevel: ErrorLevel,
+		ErrorLevel:       ErrorLevel,
+	}
+}
+
+// RoundTrip sends the request and logs the exchange.
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	logger := t.Logger
+	if logger == nil {
+		logger = DefaultLogger
+	}
+	maxBody := t.MaxBodySize
+	if maxBody <= 0 {
+		maxBody = defaultAccessLogBodySize
+	}
+
+	// Keep the head of the request body for the dump, the transport consumes and closes it
+	dump := t.DumpExchanges && enabled(logger, DebugLevel)
+	var reqBody []byte
+	var reqTruncated bool
+	if dump && req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		reqBody, reqTruncated, req.Body = peekBody(req.Body, maxBody)
+	}
+
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	latency := time.Since(start)
+
+	kv := append(contextFields(req.Context()),
+		"method", req.Method,
+		"url", req.URL.Redacted(),
+		"latency", latency,
+	)
+	if attempt := retryAttempt(req.Context()); attempt > 0 {
+		kv = append(kv, "retry", attempt)
+	}
+
+	if err != nil {
+		kv = append(kv, "err", err)
+		logAt(logger, t.ErrorLevel, "http client request failed", kv...)
+		return resp, err
+	}
+
+	kv = append(kv, "status", resp.StatusCode)
+	if dump {
+		exchange, respTruncated, err := dumpExchange(req, resp, reqBody, maxBody)
+		if err == nil {
+			kv = append(kv, "exchange", exchange)
+		} else {
+			kv = append(kv, "dump_err", err)
+		}
+		if reqTruncated {
+			kv = append(kv, "request_body_truncated", true)
+		}
+		if respTruncated {
+			kv = append(kv, "response_body_truncated", true)
+		}
+	}
+
+	level := t.Level
+	switch {
+	case resp.StatusCode >= 500:
+		level = t.ServerErrorLevel
+	case resp.StatusCode >= 400:
+		level = t.ClientErrorLevel
+	}
+	logAt(logger, level, "http client request", kv...)
+	return resp, nil
+}
+
+// dumpExchange builds the map of the exchange with the credentials masked and the response body
+// capped to max bytes, leaving the whole response body readable by the caller.
+func dumpExchange(req *http.Request, resp *http.Response, reqBody []byte, max int) (maputil.HTTPResponseMap, bool, error) {
+	dumpReq := req.Clone(req.Context())
+	maskRequestCredentials(dumpReq)
+	dumpReq.Body = http.NoBody
+	if reqBody != nil {
+		dumpReq.Body = io.NopCloser(bytes.NewReader(reqBody))
+		dumpReq.ContentLength = int64(len(reqBody))
+	}
+
+	respBody, truncated, whole := peekBody(resp.Body, max)
+	resp.Body = whole
+
+	dumpResp := *resp
+	dumpResp.Request = dumpReq
+	dumpResp.Header = resp.Header.Clone()
+	maskCredentialHeaders(dumpResp.Header)
+	dumpResp.Body = io.NopCloser(bytes.NewReader(respBody))
+	dumpResp.ContentLength = int64(len(respBody))
+	dumpResp.TransferEncoding = nil
+	exchange, err := maputil.NewHTTPResponseMap(&dumpResp)
+	return exchange, truncated, err
+}
@@ -0,0 +1,137 @@
+package logutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestLoggingTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "server-secret"})
+		_, _ = w.Write(append([]byte("echo:"), body...))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, InfoLevel, JSONEncoding)}})
+	require.Nil(t, err)
+
+	transport := NewLoggingTransport(l, nil)
+	transport.DumpExchanges = true
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(server.URL+"/echo", "text/plain", strings.NewReader("hello"))
+	require.Nil(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Equal(t, "echo:hello", string(body))
+
+	req, err := http.NewRequestWithContext(ContextWithRetryAttempt(context.Background(), 2), http.MethodGet, server.URL+"/missing", nil)
+	require.Nil(t, err)
+	resp, err = client.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 2)
+	require.Equal(t, "info", entries[0]["level"])
+	require.Equal(t, "http client request", entries[0]["msg"])
+	require.Equal(t, "POST", entries[0]["method"])
+	require.Equal(t, server.URL+"/echo", entries[0]["url"])
+	require.Equal(t, float64(200), entries[0]["status"])
+	require.Contains(t, entries[0], "latency")
+	require.NotContains(t, entries[0], "retry")
+	require.NotContains(t, entries[0], "exchange")
+
+	require.Equal(t, "warn", entries[1]["level"])
+	require.Equal(t, float64(404), entries[1]["status"])
+	require.Equal(t, float64(2), entries[1]["retry"])
+
+	// Exchanges are dumped at DebugLevel
+	buf.Reset()
+	require.Nil(t, l.(SinkLeveler).SetSinkLevel("buf", DebugLevel))
+	req, err = http.NewRequest(http.MethodPost, server.URL+"/echo", strings.NewReader("dumped"))
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer client-secret")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "cookie-secret"})
+	resp, err = client.Do(req)
+	require.Nil(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Equal(t, "echo:dumped", string(body))
+
+	entries = decodeLines(t, &buf)
+	require.Len(t, entries, 1)
+	exchange := entries[0]["exchange"].(map[string]interface{})
+	require.Equal(t, "echo:dumped", exchange["response"].(map[string]interface{})["body"])
+	require.Contains(t, exchange["request"].(map[string]interface{})["raw_without_body"], "dumped")
+	// Credentials are masked in the dump only
+	require.NotRegexp(t, "client-secret|cookie-secret|server-secret", buf.String())
+	require.Contains(t, buf.String(), DefaultRedactionMask)
+	require.Equal(t, "Bearer client-secret", req.Header.Get("Authorization"))
+	require.Len(t, resp.Cookies(), 1)
+	require.Equal(t, "server-secret", resp.Cookies()[0].Value)
+
+	// Failed requests
+	buf.Reset()
+	transport.Transport = failingTransport{}
+	_, err = client.Get(server.URL)
+	require.NotNil(t, err)
+	entries = decodeLines(t, &buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, "error", entries[0]["level"])
+	require.Equal(t, "http client request failed", entries[0]["msg"])
+	require.Equal(t, "connection refused", entries[0]["err"])
+}
+
+func TestLoggingTransportLargeBodies(t *testing.T) {
+	large := strings.Repeat("x", 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	l, err := NewLoggerE(LoggerConfig{Sinks: []Sink{NewWriterSink("buf", &buf, DebugLevel, JSONEncoding)}})
+	require.Nil(t, err)
+	defaultLogger := DefaultLogger
+	DefaultLogger = l
+	defer func() { DefaultLogger = defaultLogger }()
+
+	// The zero value logs through the DefaultLogger
+	client := &http.Client{Transport: &LoggingTransport{DumpExchanges: true, MaxBodySize: 16}}
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader(large))
+	require.Nil(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, large, string(body))
+
+	entries := decodeLines(t, &buf)
+	require.Len(t, entries, 1)
+	require.Equal(t, true, entries[0]["request_body_truncated"])
+	require.Equal(t, true, entries[0]["response_body_truncated"])
+	exchange := entries[0]["exchange"].(map[string]interface{})
+	require.Equal(t, large[:16], exchange["response"].(map[string]interface{})["body"])
+	require.NotContains(t, exchange["request"].(map[string]interface{})["raw_without_body"], large[:17])
+}
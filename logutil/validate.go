@@ -0,0 +1,123 @@
+package logutil
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// Validate reports the invalid values of the config. Every problem found is joined into the returned error.
+func (c LoggerConfig) Validate() error {
+	var errs []error
+
+	if c.ConsoleLevel > FatalLevel {
+		errs = append(errs, fmt.Errorf("invalid console level: %d", c.ConsoleLevel))
+	}
+	if c.FileLevel > FatalLevel {
+		errs = append(errs, fmt.Errorf("invalid file level: %d", c.FileLevel))
+	}
+	consoleTime := timeOptions{format: c.ConsoleTimeFormat, zone: c.ConsoleTimeZone, duration: c.ConsoleDurationFormat}
+	if err := consoleTime.validate("console"); err != nil {
+		errs = append(errs, err)
+	}
+	fileTime := timeOptions{format: c.FileTimeFormat, zone: c.FileTimeZone, duration: c.FileDurationFormat}
+	if err := fileTime.validate("file"); err != nil {
+		errs = append(errs, err)
+	}
+	if c.StacktraceLevel > FatalLevel {
+		errs = append(errs, fmt.Errorf("invalid stacktrace level: %d", c.StacktraceLevel))
+	}
+	if c.ConsoleEncoding > LogfmtEncoding {
+		errs = append(errs, fmt.Errorf("invalid console encoding: %d", c.ConsoleEncoding))
+	}
+	if c.FileEncoding > LogfmtEncoding {
+		errs = append(errs, fmt.Errorf("invalid file encoding: %d", c.FileEncoding))
+	}
+
+	if c.FileEnabled {
+		if c.LogDirectory == "" {
+			errs = append(errs, errors.New("log directory is required when file logging is enabled"))
+		}
+		if c.Filename == "" {
+			errs = append(errs, errors.New("filename is required when file logging is enabled"))
+		} else if filepath.Base(c.Filename) != c.Filename {
+			errs = append(errs, fmt.Errorf("filename must not contain a directory: %q", c.Filename))
+		}
+	}
+
+	if c.MaxSize < 0 {
+		errs = append(errs, fmt.Errorf("max size must not be negative: %d", c.MaxSize))
+	}
+	if c.MaxBackup < 0 {
+		errs = append(errs, fmt.Errorf("max backups must not be negative: %d", c.MaxBackup))
+	}
+	if c.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("max age must not be negative: %d", c.MaxAge))
+	}
+	if c.Rotation > RotateDaily {
+		errs = append(errs, fmt.Errorf("invalid rotation interval: %d", c.Rotation))
+	}
+	if c.MaxTotalSize < 0 {
+		errs = append(errs, fmt.Errorf("max total size must not be negative: %d", c.MaxTotalSize))
+	}
+
+	if c.Sampling != nil {
+		if c.Sampling.Tick < 0 {
+			errs = append(errs, fmt.Errorf("sampling tick must not be negative: %s", c.Sampling.Tick))
+		}
+		if c.Sampling.Initial < 0 {
+			errs = append(errs, fmt.Errorf("sampling initial must not be negative: %d", c.Sampling.Initial))
+		}
+		if c.Sampling.Thereafter < 0 {
+			errs = append(errs, fmt.Errorf("sampling thereafter must not be negative: %d", c.Sampling.Thereafter))
+		}
+	}
+	if c.RateLimit != nil {
+		if c.RateLimit.Rate <= 0 {
+			errs = append(errs, fmt.Errorf("rate limit must be positive: %v", c.RateLimit.Rate))
+		}
+		if c.RateLimit.Burst < 0 {
+			errs = append(errs, fmt.Errorf("rate limit burst must not be negative: %d", c.RateLimit.Burst))
+		}
+		if c.RateLimit.MaxKeys < 0 {
+			errs = append(errs, fmt.Errorf("rate limit max keys must not be negative: %d", c.RateLimit.MaxKeys))
+		}
+	}
+	if c.DropReportInterval < 0 {
+		errs = append(errs, fmt.Errorf("drop report interval must not be negative: %s", c.DropReportInterval))
+	}
+
+	if c.Async != nil {
+		if c.Async.QueueSize < 0 {
+			errs = append(errs, fmt.Errorf("async queue size must not be negative: %d", c.Async.QueueSize))
+		}
+		if c.Async.Overflow > OverflowDropOldest {
+			errs = append(errs, fmt.Errorf("invalid async overflow policy: %d", c.Async.Overflow))
+		}
+		if c.Async.FlushInterval < 0 {
+			errs = append(errs, fmt.Errorf("async flush interval must not be negative: %s", c.Async.FlushInterval))
+		}
+		if c.Async.BufferSize < 0 {
+			errs = append(errs, fmt.Errorf("async buffer size must not be negative: %d", c.Async.BufferSize))
+		}
+	}
+
+	if c.Redaction != nil {
+		if _, err := newRedactor(*c.Redaction); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	names := map[string]bool{ConsoleSinkName: true, FileSinkName: true}
+	for _, sink := range c.Sinks {
+		if err := sink.validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if names[sink.Name] {
+			errs = append(errs, fmt.Errorf("duplicate sink name: %q", sink.Name))
+		}
+		names[sink.Name] = true
+	}
+
+	return errors.Join(errs...)
+}